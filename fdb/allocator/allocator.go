@@ -0,0 +1,218 @@
+// FoundationDB Go High Contention Allocator
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Package allocator provides a high-contention allocator of short, unique
+// prefixes. Each allocation returns the tuple encoding of a small integer, so
+// the prefixes remain short, and can be used to name subspaces, for example
+// with subspace.FromBytes.
+//
+// A naive allocator that increments a single counter key causes every pair of
+// concurrent allocations to conflict. The HighContentionAllocator instead
+// chooses candidates at random from a window of integers, and only advances
+// the window (by atomically adding to a counter) once half of it has been
+// allocated. Concurrent allocations therefore conflict only when they happen
+// to choose the same candidate. This is the allocator used by the directory
+// layers of all of the FoundationDB language bindings, and it stores its state
+// in the same format.
+package allocator
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// oneBytes is the little-endian encoding of 1, for incrementing counters with
+// an atomic add.
+var oneBytes = []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+// allocatorMutex serializes the parts of Allocate that issue reads and writes
+// together, so that concurrent allocations through the same transaction see a
+// consistent order of operations.
+var allocatorMutex = sync.Mutex{}
+
+// HighContentionAllocator hands out short integer prefixes that are unique
+// among all allocations made with the same subspace. A HighContentionAllocator
+// is a lightweight value that may be copied, and is safe for concurrent use by
+// multiple goroutines.
+type HighContentionAllocator struct {
+	counters, recent subspace.Subspace
+}
+
+// New returns a HighContentionAllocator that stores its state in s. The
+// subspace must not be used for anything else, and every client allocating
+// prefixes from the same pool must use the same subspace.
+func New(s subspace.Subspace) HighContentionAllocator {
+	var hca HighContentionAllocator
+
+	hca.counters = s.Sub(0)
+	hca.recent = s.Sub(1)
+
+	return hca
+}
+
+// decodeCount decodes a little-endian counter value of up to 8 bytes.
+func decodeCount(b []byte) int64 {
+	var buf [8]byte
+	copy(buf[:], b)
+	return int64(binary.LittleEndian.Uint64(buf[:]))
+}
+
+// latestStart returns the start of the most recent allocation window, reading
+// at snapshot isolation so that advancing the window does not conflict.
+func (hca HighContentionAllocator) latestStart(tr fdb.Transaction) (int64, error) {
+	kvs, e := tr.Snapshot().GetRange(hca.counters, fdb.RangeOptions{Limit: 1, Reverse: true}).GetSliceWithError()
+	if e != nil {
+		return 0, e
+	}
+	if len(kvs) == 0 {
+		return 0, nil
+	}
+
+	t, e := hca.counters.Unpack(kvs[0].Key)
+	if e != nil {
+		return 0, e
+	}
+	return t.GetInt(0)
+}
+
+func windowSize(start int64) int64 {
+	// Larger window sizes are better for high contention, smaller sizes for
+	// keeping the keys small. But if there are many allocations, the keys
+	// can't be too small. So start small and scale up. We don't want this to
+	// ever get *too* big because we have to store about window_size/2 recent
+	// items.
+	if start < 255 {
+		return 64
+	}
+	if start < 65535 {
+		return 1024
+	}
+	return 8192
+}
+
+// Allocate returns the tuple encoding of an integer that has not been returned
+// by any other allocation from the same subspace. If t is a Database, the
+// allocation is made in a transaction of its own and retried as necessary; if
+// t is a Transaction, the allocation only takes effect if that transaction
+// commits.
+func (hca HighContentionAllocator) Allocate(t fdb.Transactor) ([]byte, error) {
+	return fdb.Transact(t, hca.allocate)
+}
+
+func (hca HighContentionAllocator) allocate(tr fdb.Transaction) ([]byte, error) {
+	for {
+		start, e := hca.latestStart(tr)
+		if e != nil {
+			return nil, e
+		}
+
+		var window int64
+		windowAdvanced := false
+
+		for {
+			allocatorMutex.Lock()
+
+			if windowAdvanced {
+				tr.ClearRange(fdb.KeyRange{Begin: hca.counters, End: hca.counters.Sub(start)})
+				tr.Options().SetNextWriteNoWriteConflictRange()
+				tr.ClearRange(fdb.KeyRange{Begin: hca.recent, End: hca.recent.Sub(start)})
+			}
+
+			// Increment the allocation count for the current window
+			tr.Add(hca.counters.Sub(start), oneBytes)
+			countFuture := tr.Snapshot().Get(hca.counters.Sub(start))
+
+			allocatorMutex.Unlock()
+
+			countBytes, e := countFuture.GetWithError()
+			if e != nil {
+				return nil, e
+			}
+
+			window = windowSize(start)
+			if decodeCount(countBytes) * 2 < window {
+				break
+			}
+
+			start += window
+			windowAdvanced = true
+		}
+
+		for {
+			// As of the snapshot being read from, the window is less than half
+			// full, so this should be expected to take 2 tries. Under high
+			// contention (and when the window advances), there is an
+			// additional subsequent risk of conflict for this transaction.
+			candidate := rand.Int63n(window) + start
+			key := hca.recent.Sub(candidate)
+
+			allocatorMutex.Lock()
+
+			latestCounter := tr.Snapshot().GetRange(hca.counters, fdb.RangeOptions{Limit: 1, Reverse: true})
+			candidateValue := tr.Snapshot().Get(key)
+			tr.Options().SetNextWriteNoWriteConflictRange()
+			tr.Set(key, []byte(""))
+
+			allocatorMutex.Unlock()
+
+			kvs, e := latestCounter.GetSliceWithError()
+			if e != nil {
+				return nil, e
+			}
+			if len(kvs) > 0 {
+				t, e := hca.counters.Unpack(kvs[0].Key)
+				if e != nil {
+					return nil, e
+				}
+				currentStart, e := t.GetInt(0)
+				if e != nil {
+					return nil, e
+				}
+				if currentStart > start {
+					// The window has advanced under us; start over.
+					break
+				}
+			}
+
+			v, e := candidateValue.GetWithError()
+			if e != nil {
+				return nil, e
+			}
+			if v == nil {
+				// The candidate was read at snapshot isolation, so claim it
+				// explicitly: a concurrent claim of the same candidate must
+				// conflict with this transaction.
+				if e := tr.AddReadConflictKey(key); e != nil {
+					return nil, e
+				}
+				if e := tr.AddWriteConflictKey(key); e != nil {
+					return nil, e
+				}
+				return tuple.Tuple{candidate}.Pack(), nil
+			}
+		}
+	}
+}
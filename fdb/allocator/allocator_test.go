@@ -0,0 +1,69 @@
+// FoundationDB Go High Contention Allocator
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package allocator
+
+import (
+	"fmt"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+)
+
+func TestLayout(t *testing.T) {
+	// The allocator of the default directory layer, as laid out by the other
+	// language bindings.
+	hca := New(subspace.FromBytes([]byte{0xfe}).Sub([]byte{0xfe}, []byte("hca")))
+
+	if got := fmt.Sprintf("%x", hca.counters.Bytes()); got != "fe01fe00016863610014" {
+		t.Errorf("counters subspace = %s", got)
+	}
+	if got := fmt.Sprintf("%x", hca.recent.Bytes()); got != "fe01fe0001686361001501" {
+		t.Errorf("recent subspace = %s", got)
+	}
+}
+
+func TestDecodeCount(t *testing.T) {
+	for _, tt := range []struct {
+		b []byte
+		n int64
+	}{
+		{nil, 0},
+		{[]byte{0x05}, 5},
+		{oneBytes, 1},
+		{[]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, 256},
+	} {
+		if n := decodeCount(tt.b); n != tt.n {
+			t.Errorf("decodeCount(%x) = %d, want %d", tt.b, n, tt.n)
+		}
+	}
+}
+
+func TestWindowSize(t *testing.T) {
+	for _, tt := range []struct {
+		start, size int64
+	}{
+		{0, 64}, {254, 64}, {255, 1024}, {65534, 1024}, {65535, 8192}, {1 << 40, 8192},
+	} {
+		if s := windowSize(tt.start); s != tt.size {
+			t.Errorf("windowSize(%d) = %d, want %d", tt.start, s, tt.size)
+		}
+	}
+}
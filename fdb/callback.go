@@ -0,0 +1,87 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"sync"
+)
+
+// The C library calls back into Go when a future becomes ready. Rather than a
+// Go pointer, which C code may not keep once the call that passed it returns,
+// it is given an integer handle, which futureHandles maps back to the future.
+// The registry also keeps the future alive until the callback has run.
+
+// handleRegistry hands out small integer handles for futures awaiting their
+// callbacks. Handles are reused once taken, so the registry does not grow
+// beyond the number of futures pending at once.
+type handleRegistry struct {
+	mutex sync.Mutex
+	futures []*future
+	free []uintptr
+}
+
+var futureHandles handleRegistry
+
+// register returns a new handle for f. Handles are never zero, so that a
+// handle cannot be mistaken for a NULL callback parameter.
+func (r *handleRegistry) register(f *future) uintptr {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if n := len(r.free); n > 0 {
+		h := r.free[n-1]
+		r.free = r.free[:n-1]
+		r.futures[h-1] = f
+		return h
+	}
+
+	r.futures = append(r.futures, f)
+	return uintptr(len(r.futures))
+}
+
+// take returns the future registered with h, and releases h.
+func (r *handleRegistry) take(h uintptr) *future {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	f := r.futures[h-1]
+	r.futures[h-1] = nil
+	r.free = append(r.free, h)
+	return f
+}
+
+// Goroutines blocked on a future wait on a channel from waiterPool, so that a
+// blocking read does not allocate a channel of its own. Each channel has room
+// for the one notification it receives before being returned to the pool.
+var waiterPool = sync.Pool{
+	New: func() interface{} {
+		return make(chan struct{}, 1)
+	},
+}
+
+func getWaiter() chan struct{} {
+	return waiterPool.Get().(chan struct{})
+}
+
+func putWaiter(w chan struct{}) {
+	waiterPool.Put(w)
+}
@@ -66,8 +66,8 @@ func (c Cluster) OpenDatabase(dbName []byte) (Database, error) {
 
 	C.fdb_future_destroy(f)
 
-	d := &database{outd}
+	d := &database{ptr: outd}
 	runtime.SetFinalizer(d, (*database).destroy)
 
-	return Database{d}, nil
+	return Database{database: d}, nil
 }
@@ -28,7 +28,10 @@ package fdb
 import "C"
 
 import (
+	"context"
+	"errors"
 	"runtime"
+	"sync"
 )
 
 // Database is a handle to a FoundationDB database. Database is a lightweight
@@ -41,6 +44,7 @@ import (
 // method.
 type Database struct {
 	*database
+	policy *RetryPolicy
 }
 
 type database struct {
@@ -64,6 +68,23 @@ func (d *database) destroy() {
 	C.fdb_database_destroy(d.ptr)
 }
 
+// SetDefaultRetryPolicy sets the RetryPolicy used by Transact, TransactContext
+// and ReadTransact, and by the convenience methods of Database built on them,
+// when called through d or a copy of d made afterwards. Other handles to the
+// same database, including those returned by other calls to Open, are not
+// affected. SetDefaultRetryPolicy must not be called while d is in use by other
+// goroutines.
+func (d *Database) SetDefaultRetryPolicy(p RetryPolicy) {
+	d.policy = &p
+}
+
+func (d Database) defaultRetryPolicy() RetryPolicy {
+	if d.policy != nil {
+		return *d.policy
+	}
+	return RetryPolicy{}
+}
+
 // CreateTransaction returns a new FoundationDB transaction. It is generally
 // preferable to use the (Database).Transact() method, which handles
 // automatically creating and committing a transaction with appropriate retry
@@ -75,7 +96,8 @@ func (d Database) CreateTransaction() (Transaction, error) {
 		return Transaction{}, Error(err)
 	}
 
-	t := &transaction{outt, d}
+	t := &transaction{ptr: outt, db: d}
+	t.newAttempt(1)
 	runtime.SetFinalizer(t, (*transaction).destroy)
 
 	return Transaction{t}, nil
@@ -89,25 +111,112 @@ func (d Database) CreateTransaction() (Transaction, error) {
 //
 // When working with fdb Future objects in a transactional fucntion, you may
 // either explicity check and return error values from (Future).GetWithError(),
-// or call (Future).GetOrPanic(). Transact will recover a panicked fdb.Error and
-// either retry the transaction or return the error.
+// or call (Future).GetOrPanic(). Transact will recover a panicked fdb.Error (or
+// an error wrapping one, such as an OpError) and either retry the transaction
+// or return the error.
+//
+// Transact retries according to the default RetryPolicy of the database, which
+// unless set with SetDefaultRetryPolicy retries for as long as OnError allows.
 //
 // See the Transactor interface for an example of using Transact with
 // Transaction and Database objects.
-func (d Database) Transact(f func(tr Transaction) (interface{}, error)) (ret interface{}, e error) {
+func (d Database) Transact(f func(tr Transaction) (interface{}, error)) (interface{}, error) {
+	return d.TransactContext(context.Background(), f)
+}
+
+// TransactContext behaves like Transact, but gives up when ctx is done. If ctx
+// is done while the caller-provided function or the commit is running, the
+// transaction is cancelled, so that any future of the transaction being waited
+// on becomes ready with an error. If ctx is done while waiting to retry, the
+// wait is abandoned. In either case, no further attempts are made, and
+// TransactContext returns ctx.Err().
+//
+// If ctx is done while the transaction is being committed, the transaction may
+// or may not have been committed, just as when Commit returns an error. If the
+// commit succeeds before the cancellation takes effect, TransactContext returns
+// the result of the function as usual.
+//
+// TransactContext retries according to the default RetryPolicy of the database.
+func (d Database) TransactContext(ctx context.Context, f func(tr Transaction) (interface{}, error)) (interface{}, error) {
+	return d.retry(ctx, d.defaultRetryPolicy(), f, commitTransaction)
+}
+
+// TransactWithPolicy behaves like TransactContext, but retries according to p
+// instead of the default RetryPolicy of the database.
+func (d Database) TransactWithPolicy(ctx context.Context, p RetryPolicy, f func(tr Transaction) (interface{}, error)) (interface{}, error) {
+	return d.retry(ctx, p, f, commitTransaction)
+}
+
+// ReadTransact runs a caller-provided function inside a retry loop, providing
+// it with a newly created transaction. After the function returns, the
+// transaction is not committed, so ReadTransact is cheaper than Transact for
+// functions that only read. Errors are retried or returned as for Transact.
+//
+// The transaction is passed to the function as a ReadTransaction, so the
+// function cannot write to it without a type assertion. Any writes that are
+// made anyway will be discarded.
+//
+// ReadTransact makes Database satisfy the ReadTransactor interface.
+func (d Database) ReadTransact(f func(rtr ReadTransaction) (interface{}, error)) (interface{}, error) {
+	return d.retry(context.Background(), d.defaultRetryPolicy(), func (tr Transaction) (interface{}, error) {
+		return f(tr)
+	}, nil)
+}
+
+// retry runs f in a new transaction until it succeeds, fails with an error
+// that OnError does not consider retryable, ctx is done or p gives up. If
+// commit is not nil, it is called to commit the transaction after each
+// successful call to f. On failure, the result of the last call to f is
+// returned along with the error.
+func (d Database) retry(ctx context.Context, p RetryPolicy, f func(tr Transaction) (interface{}, error), commit func(tr Transaction) error) (ret interface{}, e error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
 	tr, e := d.CreateTransaction()
 	/* Any error here is non-retryable */
 	if e != nil {
+		p.giveUp(0, e)
 		return
 	}
 
-	wrapped := func() {
+	if done := ctx.Done(); done != nil {
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-done:
+				tr.Cancel()
+			case <-stop:
+			}
+		}()
+
+		defer func() {
+			close(stop)
+			wg.Wait()
+		}()
+	}
+
+	return p.run(ctx, attempt(tr, f, commit), func(ep Error) error {
+		return tr.OnError(ep).GetWithContext(ctx)
+	})
+}
+
+// attempt returns a function that runs f once on tr, recovering a panic with a
+// FoundationDB error as a returned error, and then runs commit on tr if f
+// succeeded and commit is not nil.
+func attempt(tr Transaction, f func(tr Transaction) (interface{}, error), commit func(tr Transaction) error) func() (interface{}, error) {
+	return func() (ret interface{}, e error) {
 		defer func() {
 			if r := recover(); r != nil {
-				switch r := r.(type) {
-				case Error:
-					e = r
-				default:
+				if re, ok := r.(error); ok && errors.As(re, new(Error)) {
+					e = re
+				} else {
 					panic(r)
 				}
 			}
@@ -115,56 +224,34 @@ func (d Database) Transact(f func(tr Transaction) (interface{}, error)) (ret int
 
 		ret, e = f(tr)
 
-		if e != nil {
+		if e != nil || commit == nil {
 			return
 		}
 
-		e = tr.Commit().GetWithError()
+		e = commit(tr)
+		return
 	}
+}
 
-	for {
-		wrapped()
-
-		/* No error means success! */
-		if e == nil {
-			return
-		}
-
-		switch ep := e.(type) {
-		case Error:
-			e = tr.OnError(ep).GetWithError()
-		}
-
-		/* If OnError returns an error, then it's not
-		/* retryable; otherwise take another pass at things */
-		if e != nil {
-			return
-		}
-	}
+// commitTransaction commits tr and waits for the commit to complete.
+func commitTransaction(tr Transaction) error {
+	return tr.Commit().GetWithError()
 }
 
 // Get returns the value associated with the specified key (or nil if the key
 // does not exist). This read blocks the current goroutine until complete.
 func (d Database) Get(key KeyConvertible) ([]byte, error) {
-	v, e := d.Transact(func (tr Transaction) (interface{}, error) {
-		return tr.Get(key).GetOrPanic(), nil
+	return ReadTransact(d, func (rtr ReadTransaction) ([]byte, error) {
+		return rtr.Get(key).GetOrPanic(), nil
 	})
-	if e != nil {
-		return nil, e
-	}
-	return v.([]byte), nil
 }
 
 // GetKey returns the key referenced by the specified key selector. This read
 // blocks the current goroutine until complete.
 func (d Database) GetKey(sel Selectable) (Key, error) {
-	v, e := d.Transact(func (tr Transaction) (interface{}, error) {
-		return tr.GetKey(sel).GetOrPanic(), nil
+	return ReadTransact(d, func (rtr ReadTransaction) (Key, error) {
+		return rtr.GetKey(sel).GetOrPanic(), nil
 	})
-	if e != nil {
-		return nil, e
-	}
-	return v.(Key), nil
 }
 
 // GetRange returns a slice of KeyValue objects kv such that beginKey <= kv.Key
@@ -172,13 +259,9 @@ func (d Database) GetKey(sel Selectable) (Key, error) {
 // the key selectors r.BeginKeySelector() and r.EndKeySelector(). This read
 // blocks the current goroutine until complete.
 func (d Database) GetRange(r Range, options RangeOptions) ([]KeyValue, error) {
-	v, e := d.Transact(func (tr Transaction) (interface{}, error) {
-		return tr.GetRange(r, options).GetSliceOrPanic(), nil
+	return ReadTransact(d, func (rtr ReadTransaction) ([]KeyValue, error) {
+		return rtr.GetRange(r, options).GetSliceOrPanic(), nil
 	})
-	if e != nil {
-		return nil, e
-	}
-	return v.([]KeyValue), nil
 }
 
 // Set associates the specified key and value, overwriting any previous value
@@ -228,17 +311,16 @@ func (d Database) ClearRange(er ExactRange) error {
 // value associated with the key changes. This read blocks the current goroutine
 // until complete.
 func (d Database) GetAndWatch(key KeyConvertible) ([]byte, FutureNil, error) {
-	r, e := d.Transact(func (tr Transaction) (interface{}, error) {
+	var w FutureNil
+	v, e := Transact(d, func (tr Transaction) ([]byte, error) {
 		v := tr.Get(key).GetOrPanic()
-		w := tr.Watch(key)
-		return struct{value []byte; watch FutureNil}{v, w}, nil
-		return nil, nil
+		w = tr.Watch(key)
+		return v, nil
 	})
 	if e != nil {
 		return nil, FutureNil{}, e
 	}
-	ret := r.(struct{value []byte; watch FutureNil})
-	return ret.value, ret.watch, nil
+	return v, w, nil
 }
 
 // SetAndWatch associates the specified key and value, overwriting any previous
@@ -246,14 +328,10 @@ func (d Database) GetAndWatch(key KeyConvertible) ([]byte, FutureNil, error) {
 // the value associated with the key changes. This change will be committed
 // immediately and blocks the current goroutine until complete.
 func (d Database) SetAndWatch(key KeyConvertible, value []byte) (FutureNil, error) {
-	r, e := d.Transact(func (tr Transaction) (interface{}, error) {
+	return Transact(d, func (tr Transaction) (FutureNil, error) {
 		tr.Set(key, value)
 		return tr.Watch(key), nil
 	})
-	if e != nil {
-		return FutureNil{}, e
-	}
-	return r.(FutureNil), nil
 }
 
 // Clear removes the specified key (and any associated value), if it exists, and
@@ -261,14 +339,10 @@ func (d Database) SetAndWatch(key KeyConvertible, value []byte) (FutureNil, erro
 // key changes. This change will be committed immediately and blocks the current
 // goroutine until complete.
 func (d Database) ClearAndWatch(key KeyConvertible) (FutureNil, error) {
-	r, e := d.Transact(func (tr Transaction) (interface{}, error) {
+	return Transact(d, func (tr Transaction) (FutureNil, error) {
 		tr.Clear(key)
 		return tr.Watch(key), nil
 	})
-	if e != nil {
-		return FutureNil{}, e
-	}
-	return r.(FutureNil), nil
 }
 
 // Options returns a DatabaseOptions instance suitable for setting options
@@ -281,13 +355,9 @@ func (d Database) Options() DatabaseOptions {
 // the storage servers responsible for storing key and its associated
 // value. This read blocks the current goroutine until complete.
 func (d Database) LocalityGetAddressesForKey(key KeyConvertible) ([]string, error) {
-	v, e := d.Transact(func (tr Transaction) (interface{}, error) {
-		return tr.LocalityGetAddressesForKey(key).GetOrPanic(), nil
+	return ReadTransact(d, func (rtr ReadTransaction) ([]string, error) {
+		return rtr.LocalityGetAddressesForKey(key).GetOrPanic(), nil
 	})
-	if e != nil {
-		return nil, e
-	}
-	return v.([]string), nil
 }
 
 // LocalityGetBoundaryKeys returns a slice of keys that fall within the range
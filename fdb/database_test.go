@@ -0,0 +1,83 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAttempt(t *testing.T) {
+	tr := Transaction{&transaction{}}
+	failed := errors.New("failed")
+
+	for i, c := range []struct {
+		f func(Transaction) (interface{}, error)
+		commit bool
+		commitErr error
+		commits int
+		e error
+	}{
+		/* Read-only, as run by ReadTransact: never committed */
+		{func(Transaction) (interface{}, error) { return 1, nil }, false, nil, 0, nil},
+		{func(Transaction) (interface{}, error) { return 1, failed }, false, nil, 0, failed},
+		{func(Transaction) (interface{}, error) { panic(ErrorNotCommitted) }, false, nil, 0, ErrorNotCommitted},
+
+		/* As run by Transact: committed only if f succeeds */
+		{func(Transaction) (interface{}, error) { return 1, nil }, true, nil, 1, nil},
+		{func(Transaction) (interface{}, error) { return 1, nil }, true, ErrorNotCommitted, 1, ErrorNotCommitted},
+		{func(Transaction) (interface{}, error) { return 1, failed }, true, nil, 0, failed},
+		{func(Transaction) (interface{}, error) { panic(ErrorNotCommitted) }, true, nil, 0, ErrorNotCommitted},
+	} {
+		commits := 0
+		var commit func(Transaction) error
+		if c.commit {
+			commit = func(ctr Transaction) error {
+				if ctr != tr {
+					t.Errorf("Case %d: commit got a different transaction", i)
+				}
+				commits += 1
+				return c.commitErr
+			}
+		}
+
+		_, e := attempt(tr, c.f, commit)()
+		if e != c.e {
+			t.Errorf("Case %d: attempt returned %v, want %v", i, e, c.e)
+		}
+		if commits != c.commits {
+			t.Errorf("Case %d: committed %d times, want %d", i, commits, c.commits)
+		}
+	}
+}
+
+func TestAttemptPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "not an FDB error" {
+			t.Errorf("attempt recovered from a panic without an FDB error (%v)", r)
+		}
+	}()
+
+	attempt(Transaction{&transaction{}}, func(Transaction) (interface{}, error) {
+		panic("not an FDB error")
+	}, nil)()
+}
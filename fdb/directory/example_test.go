@@ -0,0 +1,61 @@
+// FoundationDB Go Directory Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package directory_test
+
+import (
+	"fmt"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/directory"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+func ExampleCreateOrOpen() {
+	_ = fdb.APIVersion(100)
+	db, _ := fdb.OpenDefault()
+
+	// Open (or create) the directory for an application, and a subdirectory
+	// for its users.
+	app, e := directory.CreateOrOpen(db, []string{"app"}, nil)
+	if e != nil {
+		fmt.Println(e)
+		return
+	}
+	users, e := app.CreateOrOpen(db, []string{"users"}, nil)
+	if e != nil {
+		fmt.Println(e)
+		return
+	}
+
+	// The DirectorySubspace packs keys under the short prefix allocated for
+	// the directory.
+	_, e = db.Transact(func (tr fdb.Transaction) (interface{}, error) {
+		tr.Set(users.Pack(tuple.Tuple{"alice"}), []byte("admin"))
+		return nil, nil
+	})
+	if e != nil {
+		fmt.Println(e)
+		return
+	}
+
+	names, _ := app.List(db, nil)
+	fmt.Println(names)
+}
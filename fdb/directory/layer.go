@@ -0,0 +1,629 @@
+// FoundationDB Go Directory Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package directory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/allocator"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+type directoryLayer struct {
+	nodeSS subspace.Subspace
+	contentSS subspace.Subspace
+
+	allowManualPrefixes bool
+
+	allocator allocator.HighContentionAllocator
+	rootNode subspace.Subspace
+
+	path []string
+}
+
+// NewDirectoryLayer returns a new root directory (as a Directory). The
+// subspaces nodeSS and contentSS control where the directory metadata and
+// contents, respectively, are stored. The default root directory has a nodeSS
+// of subspace.FromBytes([]byte{0xFE}) and a contentSS of
+// subspace.AllKeys(). Specifying more restrictive values for nodeSS and
+// contentSS will allow using the directory layer alongside other content in a
+// database.
+//
+// If allowManualPrefixes is false, all calls to CreatePrefix on the returned
+// Directory (or any subdirectories) will fail, and all directory prefixes will
+// be automatically allocated. The default root directory does not allow
+// manual prefixes.
+func NewDirectoryLayer(nodeSS, contentSS subspace.Subspace, allowManualPrefixes bool) Directory {
+	var dl directoryLayer
+
+	dl.nodeSS = subspace.FromBytes(nodeSS.Bytes())
+	dl.contentSS = subspace.FromBytes(contentSS.Bytes())
+
+	dl.allowManualPrefixes = allowManualPrefixes
+
+	dl.rootNode = dl.nodeSS.Sub(dl.nodeSS.Bytes())
+	dl.allocator = allocator.New(dl.rootNode.Sub([]byte("hca")))
+
+	return dl
+}
+
+func (dl directoryLayer) createOrOpen(tr fdb.Transaction, path []string, layer []byte, prefix []byte, allowCreate, allowOpen bool) (DirectorySubspace, error) {
+	if e := dl.checkVersion(tr, nil); e != nil {
+		return nil, e
+	}
+
+	if prefix != nil && !dl.allowManualPrefixes {
+		if len(dl.path) == 0 {
+			return nil, errors.New("Cannot specify a prefix unless manual prefixes are enabled")
+		}
+		return nil, errors.New("Cannot specify a prefix in a partition")
+	}
+
+	if len(path) == 0 {
+		return nil, errors.New("The root directory cannot be opened")
+	}
+
+	existingNode := dl.find(tr, path).prefetchMetadata(tr)
+	if existingNode.exists() {
+		if existingNode.isInPartition(tr, false) {
+			subpath := existingNode.getPartitionSubpath()
+			enc, e := existingNode.getContents(dl, tr)
+			if e != nil {
+				return nil, e
+			}
+			return enc.(directoryPartition).createOrOpen(tr, subpath, layer, prefix, allowCreate, allowOpen)
+		}
+
+		if !allowOpen {
+			return nil, ErrDirAlreadyExists
+		}
+
+		if layer != nil {
+			l, e := existingNode.layer(tr).GetWithError()
+			if e != nil {
+				return nil, e
+			}
+			if !bytes.Equal(l, layer) {
+				return nil, errors.New("The directory was created with an incompatible layer")
+			}
+		}
+
+		return existingNode.getContents(dl, tr)
+	}
+
+	if !allowCreate {
+		return nil, ErrDirNotExists
+	}
+
+	if e := dl.checkVersion(tr, &tr); e != nil {
+		return nil, e
+	}
+
+	if prefix == nil {
+		p, e := dl.allocator.Allocate(tr)
+		if e != nil {
+			return nil, fmt.Errorf("Unable to allocate new directory prefix (%s)", e.Error())
+		}
+
+		cs := dl.contentSS.Bytes()
+		prefix = append(cs[:len(cs):len(cs)], p...)
+
+		empty, e := isRangeEmpty(tr, prefix)
+		if e != nil {
+			return nil, e
+		}
+		if !empty {
+			return nil, fmt.Errorf("The database has keys stored at the prefix chosen by the automatic prefix allocator: %x", prefix)
+		}
+
+		pf, e := dl.isPrefixFree(tr.Snapshot(), prefix)
+		if e != nil {
+			return nil, e
+		}
+		if !pf {
+			return nil, errors.New("The directory layer has manually allocated prefixes that conflict with the automatic prefix allocator")
+		}
+	} else {
+		pf, e := dl.isPrefixFree(tr, prefix)
+		if e != nil {
+			return nil, e
+		}
+		if !pf {
+			return nil, errors.New("The given prefix is already in use")
+		}
+	}
+
+	var parentNode subspace.Subspace
+
+	if len(path) > 1 {
+		pd, e := dl.createOrOpen(tr, path[:len(path)-1], nil, nil, true, true)
+		if e != nil {
+			return nil, e
+		}
+		parentNode = dl.nodeWithPrefix(pd.Bytes())
+	} else {
+		parentNode = dl.rootNode
+	}
+
+	if parentNode == nil {
+		return nil, ErrParentDirDoesNotExist
+	}
+
+	node := dl.nodeWithPrefix(prefix)
+	tr.Set(parentNode.Sub(_SUBDIRS, path[len(path)-1]), prefix)
+
+	if layer == nil {
+		layer = []byte{}
+	}
+
+	tr.Set(node.Sub([]byte("layer")), layer)
+
+	return dl.contentsOfNode(node, path, layer)
+}
+
+func (dl directoryLayer) CreateOrOpen(t fdb.Transactor, path []string, layer []byte) (DirectorySubspace, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
+		return dl.createOrOpen(tr, path, layer, nil, true, true)
+	})
+}
+
+func (dl directoryLayer) Create(t fdb.Transactor, path []string, layer []byte) (DirectorySubspace, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
+		return dl.createOrOpen(tr, path, layer, nil, true, false)
+	})
+}
+
+func (dl directoryLayer) CreatePrefix(t fdb.Transactor, path []string, layer []byte, prefix []byte) (DirectorySubspace, error) {
+	if prefix == nil {
+		prefix = []byte{}
+	}
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
+		return dl.createOrOpen(tr, path, layer, prefix, true, false)
+	})
+}
+
+func (dl directoryLayer) Open(t fdb.Transactor, path []string, layer []byte) (DirectorySubspace, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
+		return dl.createOrOpen(tr, path, layer, nil, false, true)
+	})
+}
+
+func (dl directoryLayer) Exists(rt fdb.ReadTransactor, path []string) (bool, error) {
+	return fdb.ReadTransact(rt, func (rtr fdb.ReadTransaction) (bool, error) {
+		if e := dl.checkVersion(rtr, nil); e != nil {
+			return false, e
+		}
+
+		node := dl.find(rtr, path).prefetchMetadata(rtr)
+		if !node.exists() {
+			return false, nil
+		}
+
+		if node.isInPartition(rtr, false) {
+			nc, e := node.getContents(dl, rtr)
+			if e != nil {
+				return false, e
+			}
+			return nc.(directoryPartition).Exists(rtr, node.getPartitionSubpath())
+		}
+
+		return true, nil
+	})
+}
+
+func (dl directoryLayer) List(rt fdb.ReadTransactor, path []string) ([]string, error) {
+	return fdb.ReadTransact(rt, func (rtr fdb.ReadTransaction) ([]string, error) {
+		if e := dl.checkVersion(rtr, nil); e != nil {
+			return nil, e
+		}
+
+		node := dl.find(rtr, path).prefetchMetadata(rtr)
+		if !node.exists() {
+			return nil, ErrDirNotExists
+		}
+
+		if node.isInPartition(rtr, true) {
+			nc, e := node.getContents(dl, rtr)
+			if e != nil {
+				return nil, e
+			}
+			return nc.(directoryPartition).List(rtr, node.getPartitionSubpath())
+		}
+
+		return dl.subdirNames(rtr, node.subspace)
+	})
+}
+
+func (dl directoryLayer) MoveTo(t fdb.Transactor, newAbsolutePath []string) (DirectorySubspace, error) {
+	return nil, errors.New("The root directory cannot be moved")
+}
+
+func (dl directoryLayer) Move(t fdb.Transactor, oldPath []string, newPath []string) (DirectorySubspace, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
+		if e := dl.checkVersion(tr, &tr); e != nil {
+			return nil, e
+		}
+
+		if len(oldPath) == 0 {
+			return nil, errors.New("The root directory cannot be moved")
+		}
+		if len(newPath) == 0 {
+			return nil, errors.New("The root directory cannot be overwritten")
+		}
+
+		if len(newPath) >= len(oldPath) && stringsEqual(oldPath, newPath[:len(oldPath)]) {
+			return nil, errors.New("The destination directory cannot be a subdirectory of the source directory")
+		}
+
+		oldNode := dl.find(tr, oldPath).prefetchMetadata(tr)
+		newNode := dl.find(tr, newPath).prefetchMetadata(tr)
+
+		if !oldNode.exists() {
+			return nil, errors.New("The source directory does not exist")
+		}
+
+		if oldNode.isInPartition(tr, false) || newNode.isInPartition(tr, false) {
+			if !oldNode.isInPartition(tr, false) || !newNode.isInPartition(tr, false) || !stringsEqual(oldNode.path, newNode.path) {
+				return nil, errors.New("Cannot move between partitions")
+			}
+
+			nnc, e := newNode.getContents(dl, tr)
+			if e != nil {
+				return nil, e
+			}
+			return nnc.(directoryPartition).Move(tr, oldNode.getPartitionSubpath(), newNode.getPartitionSubpath())
+		}
+
+		if newNode.exists() {
+			return nil, errors.New("The destination directory already exists. Remove it first")
+		}
+
+		parentNode := dl.find(tr, newPath[:len(newPath)-1])
+		if !parentNode.exists() {
+			return nil, errors.New("The parent of the destination directory does not exist. Create it first")
+		}
+
+		p, e := dl.nodeSS.Unpack(oldNode.subspace)
+		if e != nil {
+			return nil, e
+		}
+		prefix, e := p.GetBytes(0)
+		if e != nil {
+			return nil, e
+		}
+		tr.Set(parentNode.subspace.Sub(_SUBDIRS, newPath[len(newPath)-1]), prefix)
+
+		dl.removeFromParent(tr, oldPath)
+
+		l, e := oldNode.layer(tr).GetWithError()
+		if e != nil {
+			return nil, e
+		}
+		return dl.contentsOfNode(oldNode.subspace, newPath, l)
+	})
+}
+
+func (dl directoryLayer) Remove(t fdb.Transactor, path []string) (bool, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (bool, error) {
+		if e := dl.checkVersion(tr, &tr); e != nil {
+			return false, e
+		}
+
+		if len(path) == 0 {
+			return false, errors.New("The root directory cannot be removed")
+		}
+
+		node := dl.find(tr, path).prefetchMetadata(tr)
+
+		if !node.exists() {
+			return false, nil
+		}
+
+		if node.isInPartition(tr, false) {
+			nc, e := node.getContents(dl, tr)
+			if e != nil {
+				return false, e
+			}
+			return nc.(directoryPartition).directoryLayer.Remove(tr, node.getPartitionSubpath())
+		}
+
+		if e := dl.removeRecursive(tr, node.subspace); e != nil {
+			return false, e
+		}
+		dl.removeFromParent(tr, path)
+
+		return true, nil
+	})
+}
+
+func (dl directoryLayer) GetLayer() []byte {
+	return []byte{}
+}
+
+func (dl directoryLayer) GetPath() []string {
+	return dl.path
+}
+
+func (dl directoryLayer) removeRecursive(tr fdb.Transaction, node subspace.Subspace) error {
+	nodes, e := dl.subdirNodes(tr, node)
+	if e != nil {
+		return e
+	}
+	for _, n := range(nodes) {
+		if e := dl.removeRecursive(tr, n); e != nil {
+			return e
+		}
+	}
+
+	p, e := dl.nodeSS.Unpack(node)
+	if e != nil {
+		return e
+	}
+	prefix, e := p.GetBytes(0)
+	if e != nil {
+		return e
+	}
+
+	tr.ClearRange(fdb.KeyRange{Begin: fdb.Key(prefix), End: fdb.Key(strinc(prefix))})
+	tr.ClearRange(node)
+
+	return nil
+}
+
+func (dl directoryLayer) removeFromParent(tr fdb.Transaction, path []string) {
+	parent := dl.find(tr, path[:len(path)-1])
+	tr.Clear(parent.subspace.Sub(_SUBDIRS, path[len(path)-1]))
+}
+
+func (dl directoryLayer) subdirNames(rtr fdb.ReadTransaction, node subspace.Subspace) ([]string, error) {
+	sd := node.Sub(_SUBDIRS)
+
+	kvs, e := rtr.GetRange(sd, fdb.RangeOptions{}).GetSliceWithError()
+	if e != nil {
+		return nil, e
+	}
+
+	ret := make([]string, 0, len(kvs))
+	for _, kv := range(kvs) {
+		p, e := sd.Unpack(kv.Key)
+		if e != nil {
+			return nil, e
+		}
+		name, e := p.GetString(0)
+		if e != nil {
+			return nil, e
+		}
+		ret = append(ret, name)
+	}
+
+	return ret, nil
+}
+
+func (dl directoryLayer) subdirNodes(tr fdb.Transaction, node subspace.Subspace) ([]subspace.Subspace, error) {
+	sd := node.Sub(_SUBDIRS)
+
+	kvs, e := tr.GetRange(sd, fdb.RangeOptions{}).GetSliceWithError()
+	if e != nil {
+		return nil, e
+	}
+
+	ret := make([]subspace.Subspace, 0, len(kvs))
+	for _, kv := range(kvs) {
+		ret = append(ret, dl.nodeWithPrefix(kv.Value))
+	}
+
+	return ret, nil
+}
+
+func (dl directoryLayer) nodeContainingKey(rtr fdb.ReadTransaction, key []byte) (subspace.Subspace, error) {
+	if bytes.HasPrefix(key, dl.nodeSS.Bytes()) {
+		return dl.rootNode, nil
+	}
+
+	kr := fdb.KeyRange{Begin: dl.nodeSS.BeginKey(), End: fdb.Key(append(dl.nodeSS.Pack(tuple.Tuple{key}), 0x00))}
+
+	kvs, e := rtr.GetRange(kr, fdb.RangeOptions{Reverse: true, Limit: 1}).GetSliceWithError()
+	if e != nil {
+		return nil, e
+	}
+	if len(kvs) == 1 {
+		pp, e := dl.nodeSS.Unpack(kvs[0].Key)
+		if e != nil {
+			return nil, e
+		}
+		prevPrefix, e := pp.GetBytes(0)
+		if e != nil {
+			return nil, e
+		}
+		if bytes.HasPrefix(key, prevPrefix) {
+			return dl.nodeWithPrefix(prevPrefix), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (dl directoryLayer) isPrefixFree(rtr fdb.ReadTransaction, prefix []byte) (bool, error) {
+	if len(prefix) == 0 {
+		return false, nil
+	}
+
+	nn, e := dl.nodeContainingKey(rtr, prefix)
+	if e != nil {
+		return false, e
+	}
+	if nn != nil {
+		return false, nil
+	}
+
+	kr := fdb.KeyRange{Begin: dl.nodeSS.Pack(tuple.Tuple{prefix}), End: dl.nodeSS.Pack(tuple.Tuple{strinc(prefix)})}
+
+	kvs, e := rtr.GetRange(kr, fdb.RangeOptions{Limit: 1}).GetSliceWithError()
+	if e != nil {
+		return false, e
+	}
+
+	return len(kvs) == 0, nil
+}
+
+// checkVersion returns an error if the directory layer cannot be read, or if tr
+// is not nil and the directory layer cannot be written, by this version of the
+// directory layer. If tr is not nil and the directory layer is new, its version
+// is written in tr.
+func (dl directoryLayer) checkVersion(rtr fdb.ReadTransaction, tr *fdb.Transaction) error {
+	version, e := rtr.Get(dl.rootNode.Sub([]byte("version"))).GetWithError()
+	if e != nil {
+		return e
+	}
+
+	if version == nil {
+		if tr != nil {
+			dl.initializeDirectory(*tr)
+		}
+		return nil
+	}
+
+	if len(version) != 12 {
+		return fmt.Errorf("Malformed directory layer version %x", version)
+	}
+
+	var versions [3]int32
+	for i := range(versions) {
+		versions[i] = int32(binary.LittleEndian.Uint32(version[i*4:]))
+	}
+
+	if versions[0] > _MAJORVERSION {
+		return fmt.Errorf("Cannot load directory with version %d.%d.%d using directory layer %d.%d.%d", versions[0], versions[1], versions[2], _MAJORVERSION, _MINORVERSION, _MICROVERSION)
+	}
+
+	if versions[1] > _MINORVERSION && tr != nil {
+		return fmt.Errorf("Directory with version %d.%d.%d is read-only when opened using directory layer %d.%d.%d", versions[0], versions[1], versions[2], _MAJORVERSION, _MINORVERSION, _MICROVERSION)
+	}
+
+	return nil
+}
+
+func (dl directoryLayer) initializeDirectory(tr fdb.Transaction) {
+	version := make([]byte, 12)
+	binary.LittleEndian.PutUint32(version[0:], uint32(_MAJORVERSION))
+	binary.LittleEndian.PutUint32(version[4:], uint32(_MINORVERSION))
+	binary.LittleEndian.PutUint32(version[8:], uint32(_MICROVERSION))
+
+	tr.Set(dl.rootNode.Sub([]byte("version")), version)
+}
+
+// find resolves path to a node, stopping early at a partition, whose contents
+// are managed by the partition's own directory layer.
+func (dl directoryLayer) find(rtr fdb.ReadTransaction, path []string) *node {
+	n := &node{dl.rootNode, []string{}, path, nil}
+	for i := range(path) {
+		n = &node{dl.nodeWithPrefix(rtr.Get(n.subspace.Sub(_SUBDIRS, path[i])).GetOrPanic()), path[:i+1], path, nil}
+		if !n.exists() || bytes.Equal(n.layer(rtr).GetOrPanic(), []byte("partition")) {
+			return n
+		}
+	}
+	return n
+}
+
+func (dl directoryLayer) partitionSubpath(lpath, rpath []string) []string {
+	r := make([]string, len(lpath) - len(dl.path) + len(rpath))
+	copy(r, lpath[len(dl.path):])
+	copy(r[len(lpath) - len(dl.path):], rpath)
+	return r
+}
+
+func (dl directoryLayer) contentsOfNode(node subspace.Subspace, path []string, layer []byte) (DirectorySubspace, error) {
+	p, e := dl.nodeSS.Unpack(node)
+	if e != nil {
+		return nil, e
+	}
+	prefix, e := p.GetBytes(0)
+	if e != nil {
+		return nil, e
+	}
+
+	newPath := make([]string, len(dl.path) + len(path))
+	copy(newPath, dl.path)
+	copy(newPath[len(dl.path):], path)
+
+	ss := subspace.FromBytes(prefix)
+
+	if bytes.Equal(layer, []byte("partition")) {
+		nssb := make([]byte, len(prefix) + 1)
+		copy(nssb, prefix)
+		nssb[len(prefix)] = 0xFE
+		ndl := NewDirectoryLayer(subspace.FromBytes(nssb), ss, false).(directoryLayer)
+		ndl.path = newPath
+		return directoryPartition{ndl, dl}, nil
+	}
+
+	return directorySubspace{ss, dl, newPath, layer}, nil
+}
+
+func (dl directoryLayer) nodeWithPrefix(prefix []byte) subspace.Subspace {
+	if prefix == nil {
+		return nil
+	}
+	return dl.nodeSS.Sub(prefix)
+}
+
+// isRangeEmpty returns true if no keys begin with prefix.
+func isRangeEmpty(tr fdb.Transaction, prefix []byte) (bool, error) {
+	kvs, e := tr.GetRange(fdb.KeyRange{Begin: fdb.Key(prefix), End: fdb.Key(strinc(prefix))}, fdb.RangeOptions{Limit: 1}).GetSliceWithError()
+	if e != nil {
+		return false, e
+	}
+	return len(kvs) == 0, nil
+}
+
+// strinc returns the first key that does not begin with prefix. If prefix
+// consists entirely of 0xFF bytes, there is no such key within the user
+// keyspace, and strinc returns 0xFF.
+func strinc(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			ret := make([]byte, i+1)
+			copy(ret, prefix[:i+1])
+			ret[i] += 1
+			return ret
+		}
+	}
+
+	return []byte{0xFF}
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, v := range(a) {
+		if v != b[i] {
+			return false
+		}
+	}
+	return true
+}
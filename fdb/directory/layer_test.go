@@ -0,0 +1,94 @@
+// FoundationDB Go Directory Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package directory
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// The keys under which the default directory layer stores its metadata, as
+// written by the other language bindings.
+func TestMetadataKeys(t *testing.T) {
+	dl := root.(directoryLayer)
+
+	for _, tt := range []struct {
+		name string
+		got []byte
+		want string
+	}{
+		{"root node", dl.rootNode.Bytes(), "fe01fe00"},
+		{"version", dl.rootNode.Pack(tuple.Tuple{[]byte("version")}), "fe01fe000176657273696f6e00"},
+		{"subdir", dl.rootNode.Pack(tuple.Tuple{_SUBDIRS, "app"}), "fe01fe00140261707000"},
+		{"node", dl.nodeWithPrefix([]byte{0x15, 0x01}).Bytes(), "fe01150100"},
+		{"layer", dl.nodeWithPrefix([]byte{0x15, 0x01}).Pack(tuple.Tuple{[]byte("layer")}), "fe01150100016c6179657200"},
+	} {
+		if got := fmt.Sprintf("%x", tt.got); got != tt.want {
+			t.Errorf("%s key = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContentsOfNode(t *testing.T) {
+	dl := root.(directoryLayer)
+	node := dl.nodeWithPrefix([]byte{0x15, 0x07})
+
+	ds, e := dl.contentsOfNode(node, []string{"app", "users"}, []byte("layer"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(ds.Bytes(), []byte{0x15, 0x07}) || !reflect.DeepEqual(ds.GetPath(), []string{"app", "users"}) || !bytes.Equal(ds.GetLayer(), []byte("layer")) {
+		t.Errorf("contentsOfNode = %x %v %q", ds.Bytes(), ds.GetPath(), ds.GetLayer())
+	}
+
+	ds, e = dl.contentsOfNode(node, []string{"tenant"}, []byte("partition"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	dp, ok := ds.(directoryPartition)
+	if !ok {
+		t.Fatalf("contentsOfNode returned %T for a partition", ds)
+	}
+	if !bytes.Equal(dp.nodeSS.Bytes(), []byte{0x15, 0x07, 0xfe}) || !bytes.Equal(dp.contentSS.Bytes(), []byte{0x15, 0x07}) {
+		t.Errorf("partition has node subspace %x and content subspace %x", dp.nodeSS.Bytes(), dp.contentSS.Bytes())
+	}
+	if !reflect.DeepEqual(dp.GetPath(), []string{"tenant"}) || !bytes.Equal(dp.GetLayer(), []byte("partition")) {
+		t.Errorf("partition has path %v and layer %q", dp.GetPath(), dp.GetLayer())
+	}
+	if p := dp.partitionSubpath([]string{"tenant", "a"}, []string{"b"}); !reflect.DeepEqual(p, []string{"a", "b"}) {
+		t.Errorf("partitionSubpath = %v", p)
+	}
+}
+
+func TestNewDirectoryLayerCopiesSubspaces(t *testing.T) {
+	b := []byte("meta")
+	dl := NewDirectoryLayer(subspace.FromBytes(b), subspace.Sub("content"), true).(directoryLayer)
+	b[0] = 'X'
+
+	if !bytes.Equal(dl.nodeSS.Bytes(), []byte("meta")) || !bytes.Equal(dl.rootNode.Bytes(), append([]byte("meta"), tuple.Tuple{[]byte("meta")}.Pack()...)) {
+		t.Errorf("directory layer has node subspace %q and root node %q", dl.nodeSS.Bytes(), dl.rootNode.Bytes())
+	}
+}
@@ -0,0 +1,109 @@
+// FoundationDB Go Directory Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package directory
+
+import (
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// directoryPartition is a directory created with the layer "partition", whose
+// subdirectories are managed by a directory layer of its own, rooted at the
+// partition's prefix. All keys of a partition's subdirectories therefore share
+// the partition's prefix. A partition cannot be used as a subspace itself.
+type directoryPartition struct {
+	directoryLayer
+	parentDirectoryLayer directoryLayer
+}
+
+func (dp directoryPartition) Sub(el ...interface{}) subspace.Subspace {
+	panic("Cannot open subspace in the root of a directory partition")
+}
+
+func (dp directoryPartition) Bytes() []byte {
+	panic("Cannot get key for the root of a directory partition")
+}
+
+func (dp directoryPartition) Pack(t tuple.Tuple) fdb.Key {
+	panic("Cannot pack keys using the root of a directory partition")
+}
+
+func (dp directoryPartition) Unpack(k fdb.KeyConvertible) (tuple.Tuple, error) {
+	panic("Cannot unpack keys using the root of a directory partition")
+}
+
+func (dp directoryPartition) Contains(k fdb.KeyConvertible) bool {
+	panic("Cannot check whether a key belongs to the root of a directory partition")
+}
+
+func (dp directoryPartition) Range() fdb.KeyRange {
+	panic("Cannot get range for the root of a directory partition")
+}
+
+func (dp directoryPartition) ToFDBKey() fdb.Key {
+	panic("Cannot use the root of a directory partition as a key")
+}
+
+func (dp directoryPartition) BeginKey() fdb.Key {
+	panic("Cannot get range for the root of a directory partition")
+}
+
+func (dp directoryPartition) EndKey() fdb.Key {
+	panic("Cannot get range for the root of a directory partition")
+}
+
+func (dp directoryPartition) BeginKeySelector() fdb.KeySelector {
+	panic("Cannot get range for the root of a directory partition")
+}
+
+func (dp directoryPartition) EndKeySelector() fdb.KeySelector {
+	panic("Cannot get range for the root of a directory partition")
+}
+
+func (dp directoryPartition) GetLayer() []byte {
+	return []byte("partition")
+}
+
+// getLayerForPath returns the directory layer that manages path (relative to
+// the partition). The partition itself is managed by its parent.
+func (dp directoryPartition) getLayerForPath(path []string) directoryLayer {
+	if len(path) == 0 {
+		return dp.parentDirectoryLayer
+	}
+	return dp.directoryLayer
+}
+
+func (dp directoryPartition) MoveTo(t fdb.Transactor, newAbsolutePath []string) (DirectorySubspace, error) {
+	return moveTo(t, dp.parentDirectoryLayer, dp.path, newAbsolutePath)
+}
+
+func (dp directoryPartition) Remove(t fdb.Transactor, path []string) (bool, error) {
+	dl := dp.getLayerForPath(path)
+	return dl.Remove(t, dl.partitionSubpath(dp.path, path))
+}
+
+func (dp directoryPartition) Exists(rt fdb.ReadTransactor, path []string) (bool, error) {
+	dl := dp.getLayerForPath(path)
+	return dl.Exists(rt, dl.partitionSubpath(dp.path, path))
+}
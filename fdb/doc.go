@@ -150,6 +150,21 @@ functions, i.e. calling a function that takes a Transactor from inside another
 transactional function (see the Transactor example below). Any panic is assumed
 to be handled by an enclosing (Database).Transact() wrapper.
 
+Typed Results
+
+The Transact() methods return the result of the caller-provided function as an
+interface{}, which the caller must then type-assert. The package-level Transact
+and ReadTransact functions take any Transactor or ReadTransactor respectively,
+and return the result with its own type instead:
+
+    valueOne, e := fdb.Transact(db, func (tr fdb.Transaction) ([]byte, error) {
+        return tr.Get(fdb.Key("foo")).GetOrPanic(), nil
+    })
+
+Functions that only read can use ReadTransact, which accepts a Database,
+Transaction or Snapshot. When called with a Database, the function is retried
+like Transact, but the transaction is not committed afterwards.
+
 Streaming Modes
 
 When using GetRange() methods in the FoundationDB API, clients can request large
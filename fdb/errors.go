@@ -21,11 +21,149 @@
 
 package fdb
 
-// SOMEDAY: these (along with others) should be coming from fdb.options?
+import (
+	"fmt"
+	"time"
+)
+
+// These are the error codes of the FoundationDB client library. See
+// https://foundationdb.com/documentation/api-error-codes.html for a description
+// of each.
+//
+// Since Error is a comparable type, an error e returned by this package may be
+// tested against these codes with errors.Is(e, ErrorNotCommitted), which also
+// finds an Error wrapped by another error.
 const (
-	errorNetworkNotSetup = Error(2008)
+	ErrorOperationFailed Error = 1000
+	ErrorTimedOut Error = 1004
+	ErrorTransactionTooOld Error = 1007
+	ErrorFutureVersion Error = 1009
+	ErrorNotCommitted Error = 1020
+	ErrorCommitUnknownResult Error = 1021
+	ErrorTransactionCancelled Error = 1025
+	ErrorTransactionTimedOut Error = 1031
+	ErrorTooManyWatches Error = 1032
+	ErrorWatchesDisabled Error = 1034
+	ErrorOperationCancelled Error = 1101
+
+	ErrorPlatformError Error = 1500
+	ErrorLargeAllocFailed Error = 1501
+	ErrorPerformanceCounterError Error = 1502
+	ErrorIOError Error = 1510
+	ErrorFileNotFound Error = 1511
+	ErrorBindFailed Error = 1512
+	ErrorFileNotReadable Error = 1513
+	ErrorFileNotWritable Error = 1514
+	ErrorNoClusterFileFound Error = 1515
+	ErrorClusterFileTooLarge Error = 1516
+
+	ErrorClientInvalidOperation Error = 2000
+	ErrorCommitReadIncomplete Error = 2002
+	ErrorTestSpecificationInvalid Error = 2003
+	ErrorKeyOutsideLegalRange Error = 2004
+	ErrorInvertedRange Error = 2005
+	ErrorInvalidOptionValue Error = 2006
+	ErrorInvalidOption Error = 2007
+	ErrorNetworkNotSetup Error = 2008
+	ErrorNetworkAlreadySetup Error = 2009
+	ErrorReadVersionAlreadySet Error = 2010
+	ErrorVersionInvalid Error = 2011
+	ErrorRangeLimitsInvalid Error = 2012
+	ErrorInvalidDatabaseName Error = 2013
+	ErrorAttributeNotFound Error = 2014
+	ErrorFutureNotSet Error = 2015
+	ErrorFutureNotError Error = 2016
+	ErrorFutureReleased Error = 2017
+	ErrorInvalidMutationType Error = 2018
+
+	ErrorIncompatibleProtocolVersion Error = 2100
+	ErrorTransactionTooLarge Error = 2101
+	ErrorKeyTooLarge Error = 2102
+	ErrorValueTooLarge Error = 2103
+	ErrorConnectionStringInvalid Error = 2104
+	ErrorAddressInUse Error = 2105
+	ErrorInvalidLocalAddress Error = 2106
 
-	errorApiVersionUnset = Error(2200)
-	errorApiVersionAlreadySet = Error(2201)
-	errorApiVersionNotSupported = Error(2203)
+	ErrorAPIVersionUnset Error = 2200
+	ErrorAPIVersionAlreadySet Error = 2201
+	ErrorAPIVersionInvalid Error = 2202
+	ErrorAPIVersionNotSupported Error = 2203
+	ErrorExactModeWithoutLimits Error = 2210
+
+	ErrorUnknownError Error = 4000
+	ErrorInternalError Error = 4100
 )
+
+// IsRetryable reports whether a transaction that failed with e may succeed if
+// it is retried. These are the errors that (Transaction).OnError retries.
+func (e Error) IsRetryable() bool {
+	switch e {
+	case ErrorTransactionTooOld, ErrorFutureVersion, ErrorNotCommitted, ErrorCommitUnknownResult:
+		return true
+	}
+	return false
+}
+
+// IsMaybeCommitted reports whether a commit that failed with e may nonetheless
+// have been applied to the database. A transaction that is retried after such
+// an error must be idempotent, or check whether its first attempt succeeded.
+func (e Error) IsMaybeCommitted() bool {
+	return e == ErrorCommitUnknownResult
+}
+
+// IsRetryableNotCommitted reports whether a transaction that failed with e may
+// succeed if it is retried, and is known not to have been committed, so that
+// retrying it is safe even if it is not idempotent.
+func (e Error) IsRetryableNotCommitted() bool {
+	return e.IsRetryable() && !e.IsMaybeCommitted()
+}
+
+// OpError is returned in place of an Error by the futures of a Transaction (or
+// Snapshot), to record which operation of the transaction failed. Use
+// errors.As or errors.Is to test the underlying Error.
+type OpError struct {
+	// Op is the name of the method that started the operation, such as "Get",
+	// "GetRange" or "Commit".
+	Op string
+
+	// Key is the key read or watched by the operation, or the key of the key
+	// selector resolved by GetKey. It is nil for other operations.
+	Key Key
+
+	// Range is the range read by GetRange, and nil for other operations. If
+	// the range was read in several batches, it is what remained of the range
+	// when the failed batch was requested.
+	Range Range
+
+	// Attempt is the attempt of the transaction during which the operation was
+	// started, counting from 1. Each call to (Transaction).OnError starts a
+	// new attempt, and (Transaction).Reset starts again from 1.
+	Attempt int
+
+	// Elapsed is the time from the start of that attempt until the future of
+	// the operation was found to be ready: when the C library reported it
+	// ready, or, if it was already ready when first checked or waited on, at
+	// that first check.
+	Elapsed time.Duration
+
+	// Err is the error returned by the FoundationDB C library.
+	Err Error
+}
+
+func (e *OpError) Error() string {
+	var target string
+
+	switch {
+	case e.Range != nil:
+		target = fmt.Sprintf(" %q-%q", []byte(e.Range.BeginKeySelector().Key.ToFDBKey()), []byte(e.Range.EndKeySelector().Key.ToFDBKey()))
+	case e.Key != nil:
+		target = fmt.Sprintf(" %q", []byte(e.Key))
+	}
+
+	return fmt.Sprintf("%s%s failed on attempt %d after %v: %v", e.Op, target, e.Attempt, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the underlying Error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,93 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorPredicates(t *testing.T) {
+	for _, c := range []struct {
+		e Error
+		retryable, maybeCommitted bool
+	}{
+		{ErrorTransactionTooOld, true, false},
+		{ErrorFutureVersion, true, false},
+		{ErrorNotCommitted, true, false},
+		{ErrorCommitUnknownResult, true, true},
+		{ErrorTransactionCancelled, false, false},
+		{ErrorKeyTooLarge, false, false},
+	} {
+		if got := c.e.IsRetryable(); got != c.retryable {
+			t.Errorf("Error(%d).IsRetryable() = %v, want %v", c.e, got, c.retryable)
+		}
+		if got := c.e.IsMaybeCommitted(); got != c.maybeCommitted {
+			t.Errorf("Error(%d).IsMaybeCommitted() = %v, want %v", c.e, got, c.maybeCommitted)
+		}
+		if got := c.e.IsRetryableNotCommitted(); got != (c.retryable && !c.maybeCommitted) {
+			t.Errorf("Error(%d).IsRetryableNotCommitted() = %v", c.e, got)
+		}
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	var e error = Error(1020)
+	if !errors.Is(e, ErrorNotCommitted) {
+		t.Errorf("errors.Is(Error(1020), ErrorNotCommitted) = false")
+	}
+	if errors.Is(e, ErrorTransactionTooOld) {
+		t.Errorf("errors.Is(Error(1020), ErrorTransactionTooOld) = true")
+	}
+
+	wrapped := fmt.Errorf("reading index: %w", e)
+	if !errors.Is(wrapped, ErrorNotCommitted) {
+		t.Errorf("errors.Is did not find a wrapped Error")
+	}
+}
+
+func TestOpError(t *testing.T) {
+	for _, c := range []struct {
+		e *OpError
+		prefix string
+	}{
+		{&OpError{Op: "Get", Key: Key("foo"), Attempt: 2, Elapsed: time.Second, Err: ErrorTransactionTooOld}, `Get "foo" failed on attempt 2 after 1s: `},
+		{&OpError{Op: "GetRange", Range: KeyRange{Key("a"), Key("b\xff")}, Attempt: 1, Err: ErrorNotCommitted}, `GetRange "a"-"b\xff" failed on attempt 1 after 0s: `},
+		{&OpError{Op: "Commit", Attempt: 3, Elapsed: time.Millisecond, Err: ErrorNotCommitted}, `Commit failed on attempt 3 after 1ms: `},
+	} {
+		if s := c.e.Error(); !strings.HasPrefix(s, c.prefix) {
+			t.Errorf("OpError.Error() = %q, want prefix %q", s, c.prefix)
+		}
+
+		var e error = c.e
+		var ep Error
+		if !errors.As(e, &ep) || ep != c.e.Err {
+			t.Errorf("errors.As(%v) did not find Error(%d)", c.e.Op, c.e.Err)
+		}
+		if !errors.Is(fmt.Errorf("wrapped: %w", e), c.e.Err) {
+			t.Errorf("errors.Is(%v, %d) = false", c.e.Op, c.e.Err)
+		}
+	}
+}
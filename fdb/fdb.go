@@ -24,6 +24,7 @@ package fdb
 /*
  #define FDB_API_VERSION 100
  #include <foundationdb/fdb_c.h>
+ #include <stdint.h>
 */
 import "C"
 
@@ -37,20 +38,23 @@ import (
 /* Would put this in futures.go but for the documented issue with
 /* exports and functions in preamble
 /* (https://code.google.com/p/go-wiki/wiki/cgo#Global_functions) */
-//export notifyChannel
-func notifyChannel(ch *chan struct{}) {
-	*ch <- struct{}{}
+//export notifyFuture
+func notifyFuture(h C.uintptr_t) {
+	futureHandles.take(uintptr(h)).fire()
 }
 
 // Error represents a low-level error returned by the FoundationDB C library. An
 // Error may be returned by any FoundationDB API function that returns error, or
 // as a panic from any FoundationDB API function whose name ends with OrPanic.
 //
-// An Error may be cast to an int for comparison against the list of
-// FoundationDB error codes at
+// An Error may be compared against the named error codes of this package (such
+// as ErrorNotCommitted), which follow the list of FoundationDB error codes at
 // https://foundationdb.com/documentation/api-error-codes.html, but generally
 // should be passed to (Transaction).OnError. When using (Database).Transact,
 // non-fatal errors will be retried automatically.
+//
+// The futures of a Transaction return an Error wrapped in an OpError, which
+// records the failed operation. Use errors.As to get the Error from it.
 type Error C.fdb_error_t
 
 func (e Error) Error() string {
@@ -64,6 +68,14 @@ type Transactor interface {
 	Transact(func (tr Transaction) (interface{}, error)) (interface{}, error)
 }
 
+// A ReadTransactor represents an object that can execute a read-only
+// transactional function. Functions that accept a ReadTransactor can be called
+// with a Database, Transaction or Snapshot, and declare by doing so that they
+// do not write to the database.
+type ReadTransactor interface {
+	ReadTransact(func (rtr ReadTransaction) (interface{}, error)) (interface{}, error)
+}
+
 func setOpt(setter func(*C.uint8_t, C.int) C.fdb_error_t, param []byte) error {
 	if err := setter(byteSliceToPtr(param), C.int(len(param))); err != 0 {
 		return Error(err)
@@ -89,7 +101,7 @@ func (opt NetworkOptions) setOpt(code int, param []byte) error {
 	defer networkMutex.Unlock()
 
 	if apiVersion == 0 {
-		return errorApiVersionUnset
+		return ErrorAPIVersionUnset
 	}
 
 	return setOpt(func(p *C.uint8_t, pl C.int) C.fdb_error_t {
@@ -108,11 +120,11 @@ func APIVersion(version int) error {
 	defer networkMutex.Unlock()
 
 	if apiVersion != 0 {
-		return errorApiVersionAlreadySet
+		return ErrorAPIVersionAlreadySet
 	}
 
 	if version < 100 || version > 101 {
-		return errorApiVersionNotSupported
+		return ErrorAPIVersionNotSupported
 	}
 
 	if e := C.fdb_select_api_version_impl(C.int(version), 101); e != 0 {
@@ -157,7 +169,7 @@ func StartNetwork() error {
 	defer networkMutex.Unlock()
 
 	if apiVersion == 0 {
-		return errorApiVersionUnset
+		return ErrorAPIVersionUnset
 	}
 
 	return startNetwork()
@@ -187,7 +199,7 @@ func Open(clusterFile string, dbName []byte) (Database, error) {
 	defer networkMutex.Unlock()
 
 	if apiVersion == 0 {
-		return Database{}, errorApiVersionUnset
+		return Database{}, ErrorAPIVersionUnset
 	}
 
 	var e error
@@ -251,11 +263,11 @@ func CreateCluster(clusterFile string) (Cluster, error) {
 	defer networkMutex.Unlock()
 
 	if apiVersion == 0 {
-		return Cluster{}, errorApiVersionUnset
+		return Cluster{}, ErrorAPIVersionUnset
 	}
 
 	if !networkStarted {
-		return Cluster{}, errorNetworkNotSetup
+		return Cluster{}, ErrorNetworkNotSetup
 	}
 
 	return createCluster(clusterFile)
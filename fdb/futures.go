@@ -27,24 +27,77 @@ package fdb
  #include <foundationdb/fdb_c.h>
  #include <string.h>
 
- extern void notifyChannel(void*);
+ #include <stdint.h>
 
- void go_callback(FDBFuture* f, void* ch) {
-     notifyChannel(ch);
+ extern void notifyFuture(uintptr_t);
+
+ void go_callback(FDBFuture* f, void* h) {
+     notifyFuture((uintptr_t)h);
  }
 
- void go_set_callback(void* f, void* ch) {
-     fdb_future_set_callback(f, (FDBCallback)&go_callback, ch);
+ void go_set_callback(void* f, uintptr_t h) {
+     fdb_future_set_callback(f, (FDBCallback)&go_callback, (void*)h);
  }
 */
 import "C"
 
 import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 type future struct {
 	ptr *C.FDBFuture
+	op opInfo
+
+	/* Set once f is known to be ready. It is only set with mutex
+	/* held, but may be read without it */
+	fired atomic.Bool
+
+	/* Guards the fields below, which track the goroutines and
+	/* callbacks waiting for the future */
+	mutex sync.Mutex
+	callbackSet bool
+	firedAt time.Time
+	ready chan struct{}
+	waiter chan struct{}
+	moreWaiters []chan struct{}
+	callbacks []func()
+}
+
+// opInfo describes the transaction operation that created a future, so that an
+// error from the future can be returned as an OpError.
+type opInfo struct {
+	name string
+	key Key
+	r Range
+	attempt *attemptInfo
+}
+
+// opError returns err as an OpError if the operation of f is known, or as a
+// bare Error otherwise.
+func (f *future) opError(err C.fdb_error_t) error {
+	if f.op.name == "" {
+		return Error(err)
+	}
+
+	/* Every getter waits for f first, which records when it fired */
+	f.mutex.Lock()
+	elapsed := f.firedAt.Sub(f.op.attempt.start)
+	f.mutex.Unlock()
+
+	return &OpError{
+		Op: f.op.name,
+		Key: f.op.key,
+		Range: f.op.r,
+		Attempt: f.op.attempt.n,
+		Elapsed: elapsed,
+		Err: Error(err),
+	}
 }
 
 func (f *future) destroy() {
@@ -52,27 +105,171 @@ func (f *future) destroy() {
 }
 
 func fdb_future_block_until_ready(f *C.FDBFuture) {
-	if C.fdb_future_is_ready(f) != 0 {
+	(&future{ptr: f}).BlockUntilReady()
+}
+
+// checkFired reports whether f is known to be ready. It must be called with
+// f.mutex held. Until the callback of f is set, the C library is asked
+// directly, so that futures that are ready when first waited on never need
+// one.
+func (f *future) checkFired() bool {
+	if !f.fired.Load() && !f.callbackSet && C.fdb_future_is_ready(f.ptr) != 0 {
+		f.firedAt = time.Now()
+		f.fired.Store(true)
+	}
+	return f.fired.Load()
+}
+
+// arm reports whether the caller must set the callback of f, once it has
+// released f.mutex. It must be called with f.mutex held, after checkFired has
+// returned false. The C library may run the callback before
+// fdb_future_set_callback returns, so the mutex must not be held then.
+func (f *future) arm() bool {
+	if f.callbackSet {
+		return false
+	}
+	f.callbackSet = true
+	return true
+}
+
+// setCallback asks the C library to call fire when f becomes ready. Until
+// then, f is kept alive by the handle registry, so that it is not destroyed
+// while the C library may still call back.
+func (f *future) setCallback() {
+	C.go_set_callback(unsafe.Pointer(f.ptr), C.uintptr_t(futureHandles.register(f)))
+}
+
+// fire wakes every goroutine waiting for f, closes its ready channel, and
+// starts the callbacks registered with onReady. It is called once f is ready.
+func (f *future) fire() {
+	f.mutex.Lock()
+	f.firedAt = time.Now()
+	f.fired.Store(true)
+	ready, waiter, moreWaiters, callbacks := f.ready, f.waiter, f.moreWaiters, f.callbacks
+	f.waiter, f.moreWaiters, f.callbacks = nil, nil, nil
+	f.mutex.Unlock()
+
+	if ready != nil {
+		close(ready)
+	}
+	if waiter != nil {
+		waiter <- struct{}{}
+	}
+	for _, w := range(moreWaiters) {
+		w <- struct{}{}
+	}
+	for _, cb := range(callbacks) {
+		go cb()
+	}
+}
+
+// Ready returns a channel that is closed when the future becomes ready, for
+// use in a select statement. Every call returns the same channel.
+//
+// A future whose Ready channel has been requested, directly or by waiting on
+// the future, is not garbage collected until it becomes ready. A future that
+// may never become ready, such as that of a watch, should be cancelled once it
+// is no longer needed.
+func (f *future) Ready() <-chan struct{} {
+	f.mutex.Lock()
+	if f.ready == nil {
+		f.ready = make(chan struct{})
+		if f.checkFired() {
+			close(f.ready)
+		}
+	}
+	ready := f.ready
+	set := !f.fired.Load() && f.arm()
+	f.mutex.Unlock()
+
+	if set {
+		f.setCallback()
+	}
+	return ready
+}
+
+// onReady arranges for cb to be called in a goroutine of its own once f is
+// ready. No goroutine waits for f in the meantime.
+func (f *future) onReady(cb func()) {
+	f.mutex.Lock()
+	if f.checkFired() {
+		f.mutex.Unlock()
+		go cb()
 		return
 	}
+	f.callbacks = append(f.callbacks, cb)
+	set := f.arm()
+	f.mutex.Unlock()
+
+	if set {
+		f.setCallback()
+	}
+}
+
+// blockUntilReadyContext blocks until the future is ready or ctx is done. In
+// the latter case the future is cancelled, and ctx.Err() returned.
+func (f *future) blockUntilReadyContext(ctx context.Context) error {
+	if f.IsReady() {
+		return nil
+	}
 
-	ch := make(chan struct{}, 1)
-	C.go_set_callback(unsafe.Pointer(f), unsafe.Pointer(&ch))
-	<-ch
+	select {
+	case <-f.Ready():
+		return nil
+	case <-ctx.Done():
+		f.Cancel()
+		return ctx.Err()
+	}
 }
 
 // BlockUntilReady blocks the calling goroutine until the future is ready. A
 // future becomes ready either when it receives a value of its enclosed type (if
 // any) or is set to an error state.
 func (f *future) BlockUntilReady() {
-	fdb_future_block_until_ready(f.ptr)
+	f.wait()
+}
+
+// wait blocks the calling goroutine until fire is called, on a channel from
+// the waiter pool. If f has already fired, wait returns at once without taking
+// the mutex; if it is found to be ready by checkFired, without taking a waiter.
+func (f *future) wait() {
+	if f.fired.Load() {
+		return
+	}
+
+	f.mutex.Lock()
+	if f.checkFired() {
+		f.mutex.Unlock()
+		return
+	}
+	w := getWaiter()
+	if f.waiter == nil {
+		f.waiter = w
+	} else {
+		f.moreWaiters = append(f.moreWaiters, w)
+	}
+	set := f.arm()
+	f.mutex.Unlock()
+
+	if set {
+		f.setCallback()
+	}
+
+	<-w
+	putWaiter(w)
 }
 
 // IsReady returns true if the future is ready, and false otherwise, without
 // blocking. A future is ready either when has received a value of its enclosed
 // type (if any) or has been set to an error state.
 func (f *future) IsReady() bool {
-	return C.fdb_future_is_ready(f.ptr) != 0
+	if f.fired.Load() {
+		return true
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.checkFired()
 }
 
 // Cancel cancels a future and its associated asynchronous operation. If called
@@ -82,7 +279,48 @@ func (f *future) IsReady() bool {
 // Note that even if a future is not ready, the associated asynchronous
 // operation may already have completed and be unable to be cancelled.
 func (f *future) Cancel() {
-	C.fdb_future_cancel(f.ptr)
+	/* Futures simulated by tests have no C future */
+	if f.ptr != nil {
+		C.fdb_future_cancel(f.ptr)
+	}
+}
+
+// Waitable is satisfied by every future type of this package, and is used by
+// WaitAll and WaitAny.
+type Waitable interface {
+	BlockUntilReady()
+	IsReady() bool
+	Ready() <-chan struct{}
+}
+
+// WaitAll blocks the calling goroutine until all of the futures are ready.
+func WaitAll(futures ...Waitable) {
+	for _, f := range(futures) {
+		f.BlockUntilReady()
+	}
+}
+
+// WaitAny blocks the calling goroutine until at least one of the futures is
+// ready, and returns the index of a ready future. If no futures are given,
+// WaitAny returns -1 at once.
+func WaitAny(futures ...Waitable) int {
+	if len(futures) == 0 {
+		return -1
+	}
+
+	for i, f := range(futures) {
+		if f.IsReady() {
+			return i
+		}
+	}
+
+	cases := make([]reflect.SelectCase, len(futures))
+	for i, f := range(futures) {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.Ready())}
+	}
+
+	i, _, _ := reflect.Select(cases)
+	return i
 }
 
 // FutureValue represents the asynchronous result of a function that returns a
@@ -105,7 +343,18 @@ type futureValue struct {
 // future becomes ready either when it receives a value of its enclosed type (if
 // any) or is set to an error state.
 func (f *futureValue) BlockUntilReady() {
-	fdb_future_block_until_ready(f.ptr)
+	f.future.BlockUntilReady()
+}
+
+// Ready returns a channel that is closed when the future becomes ready, for
+// use in a select statement. Every call returns the same channel.
+//
+// A future whose Ready channel has been requested, directly or by waiting on
+// the future, is not garbage collected until it becomes ready. A future that
+// may never become ready, such as that of a watch, should be cancelled once it
+// is no longer needed.
+func (f *futureValue) Ready() <-chan struct{} {
+	return f.future.Ready()
 }
 
 // IsReady returns true if the future is ready, and false otherwise, without
@@ -138,12 +387,12 @@ func (f FutureValue) GetWithError() ([]byte, error) {
 	var value *C.uint8_t
 	var length C.int
 
-	fdb_future_block_until_ready(f.ptr)
+	f.BlockUntilReady()
 	if err := C.fdb_future_get_value(f.ptr, &present, &value, &length); err != 0 {
-		if err == 2017 {
+		if Error(err) == ErrorFutureReleased {
 			return f.v, nil
 		} else {
-			return nil, Error(err)
+			return nil, f.opError(err)
 		}
 	}
 
@@ -157,6 +406,16 @@ func (f FutureValue) GetWithError() ([]byte, error) {
 	return f.v, nil
 }
 
+// GetWithContext behaves like GetWithError, but stops waiting if ctx is done
+// before the future is ready. In that case, the future (and so every copy of
+// it) is cancelled, and ctx.Err() is returned.
+func (f FutureValue) GetWithContext(ctx context.Context) ([]byte, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.GetWithError()
+}
+
 // GetOrPanic returns a database value (or nil if there is no value), or panics
 // if the asynchronous operation associated with this future did not
 // successfully complete. The current goroutine will be blocked until the future
@@ -188,7 +447,18 @@ type futureKey struct {
 // ready. A future becomes ready either when it receives a value of
 // its enclosed type (if any) or is set to an error state.
 func (f *futureKey) BlockUntilReady() {
-	fdb_future_block_until_ready(f.ptr)
+	f.future.BlockUntilReady()
+}
+
+// Ready returns a channel that is closed when the future becomes ready, for
+// use in a select statement. Every call returns the same channel.
+//
+// A future whose Ready channel has been requested, directly or by waiting on
+// the future, is not garbage collected until it becomes ready. A future that
+// may never become ready, such as that of a watch, should be cancelled once it
+// is no longer needed.
+func (f *futureKey) Ready() <-chan struct{} {
+	return f.future.Ready()
 }
 
 // IsReady returns true if the future is ready, and false otherwise,
@@ -222,12 +492,12 @@ func (f FutureKey) GetWithError() (Key, error) {
 	var value *C.uint8_t
 	var length C.int
 
-	fdb_future_block_until_ready(f.ptr)
+	f.BlockUntilReady()
 	if err := C.fdb_future_get_key(f.ptr, &value, &length); err != 0 {
-		if err == 2017 {
+		if Error(err) == ErrorFutureReleased {
 			return f.k, nil
 		} else {
-			return nil, Error(err)
+			return nil, f.opError(err)
 		}
 	}
 
@@ -238,6 +508,16 @@ func (f FutureKey) GetWithError() (Key, error) {
 	return f.k, nil
 }
 
+// GetWithContext behaves like GetWithError, but stops waiting if ctx is done
+// before the future is ready. In that case, the future (and so every copy of
+// it) is cancelled, and ctx.Err() is returned.
+func (f FutureKey) GetWithContext(ctx context.Context) (Key, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.GetWithError()
+}
+
 // GetOrPanic returns a database key, or panics if the asynchronous operation
 // associated with this future did not successfully complete. The current
 // goroutine will be blocked until the future is ready.
@@ -260,14 +540,24 @@ type FutureNil struct {
 // this future did not successfully complete. The current goroutine will be
 // blocked until the future is ready.
 func (f FutureNil) GetWithError() error {
-	fdb_future_block_until_ready(f.ptr)
+	f.BlockUntilReady()
 	if err := C.fdb_future_get_error(f.ptr); err != 0 {
-		return Error(err)
+		return f.opError(err)
 	}
 
 	return nil
 }
 
+// GetWithContext behaves like GetWithError, but stops waiting if ctx is done
+// before the future is ready. In that case, the future (and so every copy of
+// it) is cancelled, and ctx.Err() is returned.
+func (f FutureNil) GetWithContext(ctx context.Context) error {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return e
+	}
+	return f.GetWithError()
+}
+
 // GetOrPanic panics if the asynchronous operation associated with this future
 // did not successfully complete. The current goroutine will be blocked until
 // the future is ready.
@@ -294,14 +584,14 @@ func stringRefToSlice(ptr uintptr) []byte {
 }
 
 func (f *futureKeyValueArray) GetWithError() ([]KeyValue, bool, error) {
-	fdb_future_block_until_ready(f.ptr)
+	f.BlockUntilReady()
 
 	var kvs *C.void
 	var count C.int
 	var more C.fdb_bool_t
 
 	if err := C.fdb_future_get_keyvalue_array(f.ptr, (**C.FDBKeyValue)(unsafe.Pointer(&kvs)), &count, &more); err != 0 {
-		return nil, false, Error(err)
+		return nil, false, f.opError(err)
 	}
 
 	ret := make([]KeyValue, int(count))
@@ -328,15 +618,25 @@ type FutureVersion struct {
 // operation associated with this future did not successfully complete. The
 // current goroutine will be blocked until the future is ready.
 func (f FutureVersion) GetWithError() (int64, error) {
-	fdb_future_block_until_ready(f.ptr)
+	f.BlockUntilReady()
 
 	var ver C.int64_t
 	if err := C.fdb_future_get_version(f.ptr, &ver); err != 0 {
-		return 0, Error(err)
+		return 0, f.opError(err)
 	}
 	return int64(ver), nil
 }
 
+// GetWithContext behaves like GetWithError, but stops waiting if ctx is done
+// before the future is ready. In that case, the future (and so every copy of
+// it) is cancelled, and ctx.Err() is returned.
+func (f FutureVersion) GetWithContext(ctx context.Context) (int64, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return 0, e
+	}
+	return f.GetWithError()
+}
+
 // GetOrPanic returns a database version, or panics if the asynchronous
 // operation associated with this future did not successfully complete. The
 // current goroutine will be blocked until the future is ready.
@@ -353,13 +653,13 @@ type FutureStringArray struct {
 }
 
 func (f FutureStringArray) GetWithError() ([]string, error) {
-	fdb_future_block_until_ready(f.ptr)
+	f.BlockUntilReady()
 
 	var strings **C.char
 	var count C.int
 
 	if err := C.fdb_future_get_string_array(f.ptr, (***C.char)(unsafe.Pointer(&strings)), &count); err != 0 {
-		return nil, Error(err)
+		return nil, f.opError(err)
 	}
 
 	ret := make([]string, int(count))
@@ -371,6 +671,16 @@ func (f FutureStringArray) GetWithError() ([]string, error) {
 	return ret, nil
 }
 
+// GetWithContext behaves like GetWithError, but stops waiting if ctx is done
+// before the future is ready. In that case, the future (and so every copy of
+// it) is cancelled, and ctx.Err() is returned.
+func (f FutureStringArray) GetWithContext(ctx context.Context) ([]string, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.GetWithError()
+}
+
 func (f FutureStringArray) GetOrPanic() []string {
 	val, err := f.GetWithError()
 	if err != nil {
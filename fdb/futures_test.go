@@ -0,0 +1,158 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var (
+	_ Waitable = FutureValue{}
+	_ Waitable = FutureKey{}
+	_ Waitable = FutureNil{}
+	_ Waitable = FutureVersion{}
+	_ Waitable = FutureStringArray{}
+)
+
+func TestWaitNone(t *testing.T) {
+	WaitAll()
+
+	if i := WaitAny(); i != -1 {
+		t.Errorf("WaitAny() = %d, want -1", i)
+	}
+}
+
+// pendingFutures returns n futures that wait for a simulated callback, as in
+// TestFire; each becomes ready when fire is called on it.
+func pendingFutures(n int) ([]*future, []Waitable) {
+	fs := make([]*future, n)
+	ws := make([]Waitable, n)
+	for i := range(fs) {
+		fs[i] = &future{callbackSet: true}
+		ws[i] = FutureNil{fs[i]}
+	}
+	return fs, ws
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestReady(t *testing.T) {
+	fs, _ := pendingFutures(2)
+
+	ready := fs[0].Ready()
+	if isClosed(ready) {
+		t.Fatalf("Ready channel closed before the future fired")
+	}
+	if fs[0].Ready() != ready {
+		t.Errorf("Ready returned a different channel on the second call")
+	}
+	fs[0].fire()
+	if !isClosed(ready) {
+		t.Errorf("Ready channel not closed after the future fired")
+	}
+	if fs[0].Ready() != ready {
+		t.Errorf("Ready returned a different channel after the future fired")
+	}
+
+	// Fired before the channel is first requested
+	fs[1].fire()
+	if !isClosed(fs[1].Ready()) {
+		t.Errorf("Ready channel of a fired future is not closed")
+	}
+}
+
+func TestWaitAny(t *testing.T) {
+	fs, ws := pendingFutures(3)
+
+	done := make(chan int)
+	go func() {
+		done <- WaitAny(ws...)
+	}()
+
+	select {
+	case i := <-done:
+		t.Fatalf("WaitAny returned %d before any future fired", i)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	fs[2].fire()
+	if i := <-done; i != 2 {
+		t.Errorf("WaitAny returned %d, want 2", i)
+	}
+
+	fs[0].fire()
+	if i := WaitAny(ws...); i != 0 && i != 2 {
+		t.Errorf("WaitAny returned %d, want a fired future (0 or 2)", i)
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	fs, ws := pendingFutures(3)
+
+	done := make(chan struct{})
+	go func() {
+		WaitAll(ws...)
+		close(done)
+	}()
+
+	for _, f := range([]*future{fs[2], fs[0]}) {
+		f.fire()
+		select {
+		case <-done:
+			t.Fatalf("WaitAll returned before every future fired")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	fs[1].fire()
+	<-done
+}
+
+func TestGetWithContextCancel(t *testing.T) {
+	fs, _ := pendingFutures(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- FutureNil{fs[0]}.GetWithContext(ctx)
+	}()
+
+	select {
+	case e := <-done:
+		t.Fatalf("GetWithContext returned %v before the future fired or ctx was cancelled", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancel()
+	if e := <-done; e != context.Canceled {
+		t.Errorf("GetWithContext returned %v, want %v", e, context.Canceled)
+	}
+}
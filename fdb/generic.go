@@ -0,0 +1,68 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+// Transact runs f as a transactional function on t, in the same way as
+// t.Transact, but returns the result of f as a T instead of an interface{}
+// that must be type-asserted by the caller. If t is a Database, f is retried
+// and the transaction committed as described for (Database).Transact; if t is
+// a Transaction, f is simply called with it.
+//
+// If an error is returned, the first return value is the zero value of T.
+func Transact[T any](t Transactor, f func (tr Transaction) (T, error)) (T, error) {
+	var ret T
+
+	_, e := t.Transact(func (tr Transaction) (interface{}, error) {
+		var e error
+		ret, e = f(tr)
+		return nil, e
+	})
+	if e != nil {
+		var zero T
+		return zero, e
+	}
+
+	return ret, nil
+}
+
+// ReadTransact runs f as a read-only transactional function on t, in the same
+// way as t.ReadTransact, but returns the result of f as a T instead of an
+// interface{}. If t is a Database, f is retried as described for
+// (Database).ReadTransact, and the transaction is never committed; if t is a
+// Transaction or Snapshot, f is simply called with it.
+//
+// If an error is returned, the first return value is the zero value of T.
+func ReadTransact[T any](t ReadTransactor, f func (rtr ReadTransaction) (T, error)) (T, error) {
+	var ret T
+
+	_, e := t.ReadTransact(func (rtr ReadTransaction) (interface{}, error) {
+		var e error
+		ret, e = f(rtr)
+		return nil, e
+	})
+	if e != nil {
+		var zero T
+		return zero, e
+	}
+
+	return ret, nil
+}
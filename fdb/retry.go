@@ -0,0 +1,180 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// A RetryPolicy limits and observes the retry loop of (Database).Transact and
+// (Database).ReadTransact. Whether an error is retried at all is still decided
+// by (Transaction).OnError, and OnError still imposes its own delay before each
+// retry; a RetryPolicy can only make the loop give up sooner or wait longer.
+//
+// The zero RetryPolicy retries for as long as OnError allows. A default policy
+// for all transactions run through a Database handle may be set with
+// (Database).SetDefaultRetryPolicy, and a policy for a single transaction given
+// to (Database).TransactWithPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times the transactional function may be
+	// run before giving up, including the first. Zero means no limit.
+	MaxAttempts int
+
+	// Timeout is the time after which the transaction is cancelled and no
+	// further attempts are made, measured from the start of the call. Zero
+	// means no limit. When the timeout expires, context.DeadlineExceeded is
+	// returned.
+	Timeout time.Duration
+
+	// Backoff, if not nil, returns how long to wait before the next attempt,
+	// after the given attempt (counting from 1) has failed. This is in
+	// addition to the delay imposed by OnError. See ExponentialBackoff.
+	Backoff func(attempt int) time.Duration
+
+	// Fatal lists errors that are returned to the caller at once, even if
+	// OnError would retry them.
+	Fatal []Error
+
+	// OnRetry, if not nil, is called with the attempt number and error each
+	// time a failed attempt is about to be retried. The error is the one the
+	// attempt failed with, such as an *OpError.
+	OnRetry func(attempt int, e error)
+
+	// OnGiveUp, if not nil, is called with the number of attempts made and the
+	// error to be returned, whenever the retry loop ends with an error. If the
+	// transaction could not be created, no attempts have been made.
+	OnGiveUp func(attempt int, e error)
+}
+
+func (p RetryPolicy) giveUp(attempt int, e error) {
+	if p.OnGiveUp != nil {
+		p.OnGiveUp(attempt, e)
+	}
+}
+
+func (p RetryPolicy) isFatal(e Error) bool {
+	for _, f := range(p.Fatal) {
+		if e == f {
+			return true
+		}
+	}
+	return false
+}
+
+// run calls attempt until it succeeds, fails with an error that is not an
+// Error or that onError does not consider retryable, ctx is done or p gives up,
+// and returns the result of the last call. onError is called with each Error
+// that p allows to be retried, and returns nil if it may be, as
+// (Transaction).OnError does.
+func (p RetryPolicy) run(ctx context.Context, attempt func() (interface{}, error), onError func(Error) error) (ret interface{}, e error) {
+	n := 0
+
+	for {
+		if e = ctx.Err(); e != nil {
+			break
+		}
+
+		n += 1
+		ret, e = attempt()
+
+		/* No error means success! */
+		if e == nil {
+			return
+		}
+
+		/* Once ctx is done the transaction has been cancelled, and the
+		/* error is most likely a result of that */
+		if ctx.Err() != nil {
+			e = ctx.Err()
+			break
+		}
+
+		/* Errors other than FDB Errors are never retryable */
+		var ep Error
+		if !errors.As(e, &ep) || p.isFatal(ep) || (p.MaxAttempts > 0 && n >= p.MaxAttempts) {
+			break
+		}
+
+		/* If OnError returns an error, then it's not
+		/* retryable; otherwise take another pass at things. OnError
+		/* returns the same error when fatal, but e also records
+		/* the operation that failed, so keep it */
+		if eo := onError(ep); eo != nil {
+			if eo != ep {
+				e = eo
+			}
+			break
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(n, e)
+		}
+
+		if p.Backoff != nil {
+			if e = sleepContext(ctx, p.Backoff(n)); e != nil {
+				break
+			}
+		}
+	}
+
+	p.giveUp(n, e)
+
+	return
+}
+
+// ExponentialBackoff returns a function suitable for RetryPolicy.Backoff. The
+// delay after attempt n is chosen uniformly at random between zero and base *
+// 2^(n-1), or between zero and limit if that is smaller, so that concurrent
+// clients retrying the same conflict spread out.
+func ExponentialBackoff(base, limit time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := limit
+		if shift := uint(attempt - 1); attempt >= 1 && shift < 63 && base <= limit >> shift {
+			d = base << shift
+		}
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d) + 1))
+	}
+}
+
+// sleepContext waits for d, or until ctx is done, in which case ctx.Err() is
+// returned.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,213 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(10 * time.Millisecond, time.Second)
+
+	for attempt := 1; attempt <= 100; attempt += 1 {
+		limit := time.Second
+		if attempt <= 7 {
+			limit = (10 * time.Millisecond) << uint(attempt - 1)
+		}
+		for i := 0; i < 100; i += 1 {
+			if d := b(attempt); d < 0 || d > limit {
+				t.Fatalf("Backoff after attempt %d was %v, want between 0 and %v", attempt, d, limit)
+			}
+		}
+	}
+
+	if d := ExponentialBackoff(0, 0)(1); d != 0 {
+		t.Errorf("Backoff with no limit was %v, want 0", d)
+	}
+}
+
+func TestRetryPolicyFatal(t *testing.T) {
+	p := RetryPolicy{Fatal: []Error{1007, 1020}}
+
+	for _, c := range []struct {
+		e Error
+		fatal bool
+	}{
+		{1007, true},
+		{1020, true},
+		{1021, false},
+	} {
+		if p.isFatal(c.e) != c.fatal {
+			t.Errorf("isFatal(%d) = %v, want %v", c.e, !c.fatal, c.fatal)
+		}
+	}
+
+	if (RetryPolicy{}).isFatal(1020) {
+		t.Errorf("Zero RetryPolicy treats 1020 as fatal")
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if e := sleepContext(ctx, time.Hour); e != context.Canceled {
+		t.Errorf("sleepContext with a cancelled context returned %v", e)
+	}
+	if e := sleepContext(context.Background(), time.Millisecond); e != nil {
+		t.Errorf("sleepContext returned %v", e)
+	}
+}
+
+// fakeAttempts returns a function standing in for the transactional function
+// and commit of a retry loop. Each call fails with the next of errs, and once
+// they are used up succeeds; either way the attempt number is the result.
+func fakeAttempts(errs ...error) func() (interface{}, error) {
+	n := 0
+	return func() (interface{}, error) {
+		n += 1
+		if n <= len(errs) {
+			return n, errs[n-1]
+		}
+		return n, nil
+	}
+}
+
+func retryable(Error) error {
+	return nil
+}
+
+func TestRetryPolicyRun(t *testing.T) {
+	conflict := &OpError{Op: "Commit", Err: ErrorNotCommitted}
+	tooOld := &OpError{Op: "Get", Err: ErrorTransactionTooOld}
+	other := errors.New("not an FDB error")
+
+	for i, c := range []struct {
+		p RetryPolicy
+		errs []error
+		onError func(Error) error
+		ret int
+		e error
+	}{
+		{RetryPolicy{}, []error{conflict, tooOld}, retryable, 3, nil},
+		{RetryPolicy{MaxAttempts: 2}, []error{conflict, tooOld, conflict}, retryable, 2, tooOld},
+		{RetryPolicy{MaxAttempts: 2}, []error{conflict}, retryable, 2, nil},
+		{RetryPolicy{Fatal: []Error{ErrorTransactionTooOld}}, []error{conflict, tooOld}, retryable, 2, tooOld},
+		{RetryPolicy{}, []error{other}, retryable, 1, other},
+		{RetryPolicy{}, []error{conflict}, func(e Error) error { return e }, 1, conflict},
+		{RetryPolicy{}, []error{conflict}, func(Error) error { return ErrorTransactionTooOld }, 1, ErrorTransactionTooOld},
+	} {
+		var retried []error
+		var gaveUp []error
+		var gaveUpAfter int
+		c.p.OnRetry = func(attempt int, e error) {
+			if attempt != len(retried) + 1 {
+				t.Errorf("Case %d: OnRetry called for attempt %d, want %d", i, attempt, len(retried) + 1)
+			}
+			retried = append(retried, e)
+		}
+		c.p.OnGiveUp = func(attempt int, e error) {
+			gaveUp = append(gaveUp, e)
+			gaveUpAfter = attempt
+		}
+
+		ret, e := c.p.run(context.Background(), fakeAttempts(c.errs...), c.onError)
+
+		if ret != c.ret || e != c.e {
+			t.Errorf("Case %d: run returned (%v, %v), want (%v, %v)", i, ret, e, c.ret, c.e)
+		}
+		for j, re := range(retried) {
+			if re != c.errs[j] {
+				t.Errorf("Case %d: OnRetry got %v for attempt %d, want %v", i, re, j + 1, c.errs[j])
+			}
+		}
+		if c.e == nil {
+			if len(gaveUp) != 0 {
+				t.Errorf("Case %d: OnGiveUp called after success", i)
+			}
+		} else if len(gaveUp) != 1 || gaveUp[0] != c.e || gaveUpAfter != c.ret {
+			t.Errorf("Case %d: OnGiveUp got %v after %d attempts, want %v after %d", i, gaveUp, gaveUpAfter, c.e, c.ret)
+		}
+	}
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	d := Database{database: &database{}}
+	other := d
+
+	d.SetDefaultRetryPolicy(RetryPolicy{MaxAttempts: 3})
+
+	if n := d.defaultRetryPolicy().MaxAttempts; n != 3 {
+		t.Errorf("Default MaxAttempts of d is %d, want 3", n)
+	}
+	if n := other.defaultRetryPolicy().MaxAttempts; n != 0 {
+		t.Errorf("Default MaxAttempts of another handle to the same database is %d, want 0", n)
+	}
+}
+
+func TestRetryPolicyRunContext(t *testing.T) {
+	conflict := &OpError{Op: "Commit", Err: ErrorNotCommitted}
+
+	// cancelAfter cancels a context once the retry loop has started.
+	cancelAfter := func() (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		return ctx, cancel
+	}
+
+	// Cancelled while OnError waits, as GetWithContext does
+	ctx, cancel := cancelAfter()
+	_, e := RetryPolicy{}.run(ctx, fakeAttempts(conflict), func(Error) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if e != context.Canceled {
+		t.Errorf("Cancelled during OnError, run returned %v, want %v", e, context.Canceled)
+	}
+	cancel()
+
+	// Cancelled while backing off
+	ctx, cancel = cancelAfter()
+	_, e = RetryPolicy{Backoff: func(int) time.Duration { return time.Hour }}.run(ctx, fakeAttempts(conflict), retryable)
+	if e != context.Canceled {
+		t.Errorf("Cancelled during backoff, run returned %v, want %v", e, context.Canceled)
+	}
+	cancel()
+
+	// Cancelled during an attempt, which then fails as its transaction is
+	// cancelled
+	ctx, cancel = cancelAfter()
+	_, e = RetryPolicy{}.run(ctx, func() (interface{}, error) {
+		<-ctx.Done()
+		return nil, &OpError{Op: "Get", Err: ErrorTransactionCancelled}
+	}, retryable)
+	if e != context.Canceled {
+		t.Errorf("Cancelled during an attempt, run returned %v, want %v", e, context.Canceled)
+	}
+	cancel()
+}
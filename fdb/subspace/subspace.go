@@ -0,0 +1,161 @@
+// FoundationDB Go Subspace Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Package subspace provides a convenient way to use FoundationDB tuples to
+// define namespaces for different categories of data. The namespace is
+// specified by a prefix tuple which is prepended to all tuples packed by the
+// subspace. When unpacking a key with the subspace, the prefix tuple will be
+// removed from the result.
+//
+// As a best practice, API clients should use at least one subspace for
+// application data. For general guidance on subspace usage, see the Subspaces
+// section of the Developer Guide
+// (https://foundationdb.com/documentation/developer-guide.html#developer-guide-sub-keyspaces).
+package subspace
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// Subspace represents a well-defined region of keyspace in a FoundationDB
+// database.
+//
+// A Subspace may be used wherever a KeyConvertible is accepted, in which case
+// it stands for its raw prefix, and wherever an ExactRange is accepted, in
+// which case it describes all keys strictly within the subspace (that is, all
+// keys that begin with the prefix, other than the prefix itself). This makes a
+// Subspace directly usable with the GetRange and ClearRange methods of
+// Transaction.
+type Subspace interface {
+	// Sub returns a new Subspace whose prefix extends this Subspace with the
+	// encoding of the provided tuple elements, as Pack would encode them.
+	Sub(el ...interface{}) Subspace
+
+	// Bytes returns the literal bytes of the prefix of this Subspace.
+	Bytes() []byte
+
+	// Pack returns the key encoding the specified Tuple with the prefix of
+	// this Subspace prepended.
+	Pack(t tuple.Tuple) fdb.Key
+
+	// Unpack returns the Tuple encoded by the given key with the prefix of
+	// this Subspace removed. Unpack will return an error if the key is not in
+	// this Subspace or does not encode a well-formed Tuple.
+	Unpack(k fdb.KeyConvertible) (tuple.Tuple, error)
+
+	// Contains returns true if the provided key starts with the prefix of this
+	// Subspace, indicating that the Subspace logically contains the key.
+	Contains(k fdb.KeyConvertible) bool
+
+	// Range returns the KeyRange of all keys strictly within this Subspace.
+	Range() fdb.KeyRange
+
+	// All Subspaces implement fdb.KeyConvertible and may be used as
+	// FoundationDB keys (corresponding to the prefix of this Subspace).
+	fdb.KeyConvertible
+
+	// All Subspaces implement fdb.ExactRange and fdb.Range, and describe all
+	// keys strictly within the subspace that encode tuples. Specifically, this
+	// will include all keys in [prefix + '\x00', prefix + '\xff').
+	fdb.ExactRange
+}
+
+type subspace struct {
+	b []byte
+}
+
+// prefix returns the prefix of s with its capacity limited to its length, so
+// that appending to it always allocates instead of sharing the prefix's
+// backing array.
+func (s subspace) prefix() []byte {
+	return s.b[:len(s.b):len(s.b)]
+}
+
+// AllKeys returns the Subspace corresponding to all keys in a FoundationDB
+// database.
+func AllKeys() Subspace {
+	return subspace{}
+}
+
+// Sub returns a new Subspace whose prefix is the encoding of the provided
+// tuple elements.
+func Sub(el ...interface{}) Subspace {
+	return subspace{tuple.Tuple(el).Pack()}
+}
+
+// FromBytes returns a new Subspace from the provided bytes, which are copied.
+func FromBytes(b []byte) Subspace {
+	s := make([]byte, len(b))
+	copy(s, b)
+	return subspace{s}
+}
+
+func (s subspace) Sub(el ...interface{}) Subspace {
+	return subspace{tuple.Tuple(el).PackTo(s.prefix())}
+}
+
+func (s subspace) Bytes() []byte {
+	return s.b
+}
+
+func (s subspace) Pack(t tuple.Tuple) fdb.Key {
+	return fdb.Key(t.PackTo(s.prefix()))
+}
+
+func (s subspace) Unpack(k fdb.KeyConvertible) (tuple.Tuple, error) {
+	key := k.ToFDBKey()
+	if !bytes.HasPrefix(key, s.b) {
+		return nil, fmt.Errorf("Cannot unpack key that is not contained in subspace")
+	}
+	return tuple.Unpack(key[len(s.b):])
+}
+
+func (s subspace) Contains(k fdb.KeyConvertible) bool {
+	return bytes.HasPrefix(k.ToFDBKey(), s.b)
+}
+
+func (s subspace) Range() fdb.KeyRange {
+	return fdb.KeyRange{Begin: s.BeginKey(), End: s.EndKey()}
+}
+
+func (s subspace) ToFDBKey() fdb.Key {
+	return fdb.Key(s.b)
+}
+
+func (s subspace) BeginKey() fdb.Key {
+	return fdb.Key(append(s.prefix(), 0x00))
+}
+
+func (s subspace) EndKey() fdb.Key {
+	return fdb.Key(append(s.prefix(), 0xff))
+}
+
+func (s subspace) BeginKeySelector() fdb.KeySelector {
+	return fdb.FirstGreaterOrEqual(s.BeginKey())
+}
+
+func (s subspace) EndKeySelector() fdb.KeySelector {
+	return fdb.FirstGreaterOrEqual(s.EndKey())
+}
@@ -0,0 +1,117 @@
+// FoundationDB Go Subspace Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subspace_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// Subspaces must be usable wherever keys and exact ranges are accepted.
+var (
+	_ fdb.KeyConvertible = subspace.AllKeys()
+	_ fdb.ExactRange = subspace.AllKeys()
+)
+
+func TestSubspace(t *testing.T) {
+	s := subspace.Sub("users", int64(7))
+	if !bytes.Equal(s.Bytes(), tuple.Tuple{"users", int64(7)}.Pack()) {
+		t.Errorf("Sub(\"users\", 7).Bytes() = %x", s.Bytes())
+	}
+
+	child := s.Sub("posts")
+	if !bytes.Equal(child.Bytes(), tuple.Tuple{"users", int64(7), "posts"}.Pack()) {
+		t.Errorf("Sub(\"posts\").Bytes() = %x", child.Bytes())
+	}
+
+	k := child.Pack(tuple.Tuple{int64(1), "title"})
+	if !bytes.Equal(k, tuple.Tuple{"users", int64(7), "posts", int64(1), "title"}.Pack()) {
+		t.Errorf("Pack = %x", k)
+	}
+	if !s.Contains(k) || !child.Contains(k) || subspace.Sub("users", int64(8)).Contains(k) {
+		t.Errorf("Contains(%x) returned the wrong result", k)
+	}
+
+	u, e := child.Unpack(k)
+	if e != nil || !reflect.DeepEqual(u, tuple.Tuple{int64(1), "title"}) {
+		t.Errorf("Unpack(%x) = %v, %v", k, u, e)
+	}
+	u, e = s.Unpack(k)
+	if e != nil || !reflect.DeepEqual(u, tuple.Tuple{"posts", int64(1), "title"}) {
+		t.Errorf("Unpack(%x) = %v, %v", k, u, e)
+	}
+
+	if _, e := subspace.Sub("groups").Unpack(k); e == nil {
+		t.Errorf("Unpack of a key outside the subspace did not fail")
+	}
+	if _, e := subspace.FromBytes([]byte("p")).Unpack(fdb.Key("p\x02a")); e == nil {
+		t.Errorf("Unpack of a malformed tuple did not fail")
+	}
+}
+
+func TestSubspaceRange(t *testing.T) {
+	s := subspace.FromBytes([]byte{0x01, 0xff})
+
+	kr := s.Range()
+	if !bytes.Equal(kr.BeginKey(), []byte{0x01, 0xff, 0x00}) || !bytes.Equal(kr.EndKey(), []byte{0x01, 0xff, 0xff}) {
+		t.Errorf("Range() = [%x, %x)", kr.BeginKey(), kr.EndKey())
+	}
+	if !bytes.Equal(s.BeginKey(), kr.BeginKey()) || !bytes.Equal(s.EndKey(), kr.EndKey()) {
+		t.Errorf("BeginKey and EndKey do not match Range()")
+	}
+
+	k := s.Pack(tuple.Tuple{"x"})
+	if bytes.Compare(k, s.BeginKey()) < 0 || bytes.Compare(k, s.EndKey()) >= 0 {
+		t.Errorf("%x is outside [%x, %x)", k, s.BeginKey(), s.EndKey())
+	}
+
+	all := subspace.AllKeys()
+	if !bytes.Equal(all.BeginKey(), []byte{0x00}) || !bytes.Equal(all.EndKey(), []byte{0xff}) {
+		t.Errorf("AllKeys() range is [%x, %x)", all.BeginKey(), all.EndKey())
+	}
+	if !all.Contains(k) {
+		t.Errorf("AllKeys() does not contain %x", k)
+	}
+}
+
+func TestSubspaceNoAliasing(t *testing.T) {
+	b := []byte("prefix")
+	s := subspace.FromBytes(b)
+	b[0] = 'X'
+
+	a := s.Pack(tuple.Tuple{"a"})
+	c := s.Pack(tuple.Tuple{"c"})
+	s.Sub("b")
+	_ = s.BeginKey()
+	_ = s.EndKey()
+
+	if !bytes.Equal(s.Bytes(), []byte("prefix")) {
+		t.Errorf("prefix was modified to %q", s.Bytes())
+	}
+	if !bytes.Equal(a, append([]byte("prefix"), tuple.Tuple{"a"}.Pack()...)) || !bytes.Equal(c, append([]byte("prefix"), tuple.Tuple{"c"}.Pack()...)) {
+		t.Errorf("packed keys share storage: %q, %q", a, c)
+	}
+}
@@ -29,11 +29,14 @@ import "C"
 
 import (
 	"runtime"
+	"sync/atomic"
+	"time"
 )
 
 // A ReadTransaction represents an object that can asynchronously read from a
 // FoundationDB database. Transaction and Snapshot both satisfy the
-// ReadTransaction interface.
+// ReadTransaction interface. A ReadTransaction is also a ReadTransactor, so it
+// can be passed to functions that read from a database transactionally.
 type ReadTransaction interface {
 	Get(key KeyConvertible) FutureValue
 	GetKey(sel Selectable) FutureKey
@@ -41,6 +44,8 @@ type ReadTransaction interface {
 	GetReadVersion() FutureVersion
 	GetDatabase() Database
 	LocalityGetAddressesForKey(key KeyConvertible) FutureStringArray
+
+	ReadTransactor
 }
 
 // Transaction is a handle to a FoundationDB transaction. Transaction is a
@@ -69,6 +74,27 @@ type Transaction struct {
 type transaction struct {
 	ptr *C.FDBTransaction
 	db Database
+	attempt atomic.Pointer[attemptInfo]
+}
+
+// attemptInfo numbers the attempts of a transaction, each of which begins when
+// the transaction is created, reset or passed to OnError.
+type attemptInfo struct {
+	n int
+	start time.Time
+}
+
+func (t *transaction) newAttempt(n int) {
+	t.attempt.Store(&attemptInfo{n, time.Now()})
+}
+
+// op describes an operation of the current attempt of t. key is copied, since
+// it may be reused by the caller while the operation is in flight.
+func (t *transaction) op(name string, key Key, r Range) opInfo {
+	if key != nil {
+		key = append(Key(nil), key...)
+	}
+	return opInfo{name: name, key: key, r: r, attempt: t.attempt.Load()}
 }
 
 // TransactionOptions is a handle with which to set options that affect a
@@ -106,6 +132,15 @@ func (t Transaction) Transact(f func (tr Transaction) (interface{}, error)) (int
 	return f(t)
 }
 
+// ReadTransact passes the Transaction receiver object to the caller-provided
+// function, but does not handle errors.
+//
+// ReadTransact makes Transaction satisfy the ReadTransactor interface, allowing
+// read-only transactional functions to be used compositionally.
+func (t Transaction) ReadTransact(f func (rtr ReadTransaction) (interface{}, error)) (interface{}, error) {
+	return f(t)
+}
+
 // Cancel cancels a transaction. All pending or future uses of the transaction
 // will encounter an error. The Transaction object may be reused after calling
 // (Transaction).Reset().
@@ -145,8 +180,8 @@ func (t Transaction) Snapshot() Snapshot {
 	return Snapshot{t.transaction}
 }
 
-func makeFutureNil(fp *C.FDBFuture) FutureNil {
-	f := &future{fp}
+func makeFutureNil(fp *C.FDBFuture, op opInfo) FutureNil {
+	f := &future{ptr: fp, op: op}
 	runtime.SetFinalizer(f, (*future).destroy)
 	return FutureNil{f}
 }
@@ -159,7 +194,8 @@ func makeFutureNil(fp *C.FDBFuture) FutureNil {
 // Typical code will not use OnError directly. (Database).Transact() uses
 // OnError internally to implement a correct retry loop.
 func (t Transaction) OnError(e Error) FutureNil {
-	return makeFutureNil(C.fdb_transaction_on_error(t.ptr, C.fdb_error_t(e)))
+	t.newAttempt(t.attempt.Load().n + 1)
+	return makeFutureNil(C.fdb_transaction_on_error(t.ptr, C.fdb_error_t(e)), opInfo{})
 }
 
 // Commit attempts to commit the modifications made in the transaction to the
@@ -173,7 +209,7 @@ func (t Transaction) OnError(e Error) FutureNil {
 // see
 // https://foundationdb.com/documentation/developer-guide.html#developer-guide-unknown-results.
 func (t Transaction) Commit() FutureNil {
-	return makeFutureNil(C.fdb_transaction_commit(t.ptr))
+	return makeFutureNil(C.fdb_transaction_commit(t.ptr), t.op("Commit", nil, nil))
 }
 
 // Watch creates a watch and returns a FutureNil that will become ready when the
@@ -201,11 +237,11 @@ func (t Transaction) Commit() FutureNil {
 // cancelled by calling (FutureNil).Cancel() on its returned future.
 func (t Transaction) Watch(key KeyConvertible) FutureNil {
 	kb := key.ToFDBKey()
-	return makeFutureNil(C.fdb_transaction_watch(t.ptr, byteSliceToPtr(kb), C.int(len(kb))))
+	return makeFutureNil(C.fdb_transaction_watch(t.ptr, byteSliceToPtr(kb), C.int(len(kb))), t.op("Watch", kb, nil))
 }
 
 func (t *transaction) get(key []byte, snapshot int) FutureValue {
-	f := &future{C.fdb_transaction_get(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(snapshot))}
+	f := &future{ptr: C.fdb_transaction_get(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(snapshot)), op: t.op("Get", key, nil)}
 	runtime.SetFinalizer(f, (*future).destroy)
 	return FutureValue{&futureValue{future: f}}
 }
@@ -222,7 +258,11 @@ func (t *transaction) doGetRange(r Range, options RangeOptions, snapshot bool, i
 	bkey := begin.Key.ToFDBKey()
 	end := r.EndKeySelector()
 	ekey := end.Key.ToFDBKey()
-	f := &future{C.fdb_transaction_get_range(t.ptr, byteSliceToPtr(bkey), C.int(len(bkey)), C.fdb_bool_t(boolToInt(begin.OrEqual)), C.int(begin.Offset), byteSliceToPtr(ekey), C.int(len(ekey)), C.fdb_bool_t(boolToInt(end.OrEqual)), C.int(end.Offset), C.int(options.Limit), C.int(0), C.FDBStreamingMode(options.Mode-1), C.int(iteration), C.fdb_bool_t(boolToInt(snapshot)), C.fdb_bool_t(boolToInt(options.Reverse)))}
+	op := t.op("GetRange", nil, SelectorRange{
+		KeySelector{append(Key(nil), bkey...), begin.OrEqual, begin.Offset},
+		KeySelector{append(Key(nil), ekey...), end.OrEqual, end.Offset},
+	})
+	f := &future{ptr: C.fdb_transaction_get_range(t.ptr, byteSliceToPtr(bkey), C.int(len(bkey)), C.fdb_bool_t(boolToInt(begin.OrEqual)), C.int(begin.Offset), byteSliceToPtr(ekey), C.int(len(ekey)), C.fdb_bool_t(boolToInt(end.OrEqual)), C.int(end.Offset), C.int(options.Limit), C.int(0), C.FDBStreamingMode(options.Mode-1), C.int(iteration), C.fdb_bool_t(boolToInt(snapshot)), C.fdb_bool_t(boolToInt(options.Reverse))), op: op}
 	runtime.SetFinalizer(f, (*future).destroy)
 	return futureKeyValueArray{f}
 }
@@ -248,7 +288,7 @@ func (t Transaction) GetRange(r Range, options RangeOptions) RangeResult {
 }
 
 func (t *transaction) getReadVersion() FutureVersion {
-	f := &future{C.fdb_transaction_get_read_version(t.ptr)}
+	f := &future{ptr: C.fdb_transaction_get_read_version(t.ptr), op: t.op("GetReadVersion", nil, nil)}
 	runtime.SetFinalizer(f, (*future).destroy)
 	return FutureVersion{f}
 }
@@ -307,6 +347,7 @@ func (t Transaction) GetCommittedVersion() (int64, error) {
 // creating a new one.
 func (t Transaction) Reset() {
 	C.fdb_transaction_reset(t.ptr)
+	t.newAttempt(1)
 }
 
 func boolToInt(b bool) int {
@@ -319,7 +360,7 @@ func boolToInt(b bool) int {
 
 func (t *transaction) getKey(sel KeySelector, snapshot int) FutureKey {
 	key := sel.Key.ToFDBKey()
-	f := &future{C.fdb_transaction_get_key(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(boolToInt(sel.OrEqual)), C.int(sel.Offset), C.fdb_bool_t(snapshot))}
+	f := &future{ptr: C.fdb_transaction_get_key(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(boolToInt(sel.OrEqual)), C.int(sel.Offset), C.fdb_bool_t(snapshot)), op: t.op("GetKey", key, nil)}
 	runtime.SetFinalizer(f, (*future).destroy)
 	return FutureKey{&futureKey{future: f}}
 }
@@ -402,7 +443,7 @@ func (t Transaction) Options() TransactionOptions {
 func localityGetAddressesForKey(t *transaction, key KeyConvertible) FutureStringArray {
 	kb := key.ToFDBKey()
 
-	f := &future{C.fdb_transaction_get_addresses_for_key(t.ptr, byteSliceToPtr(kb), C.int(len(kb)))}
+	f := &future{ptr: C.fdb_transaction_get_addresses_for_key(t.ptr, byteSliceToPtr(kb), C.int(len(kb))), op: t.op("LocalityGetAddressesForKey", kb, nil)}
 	runtime.SetFinalizer(f, (*future).destroy)
 	return FutureStringArray{f}
 }
@@ -426,6 +467,15 @@ type Snapshot struct {
 	*transaction
 }
 
+// ReadTransact passes the Snapshot receiver object to the caller-provided
+// function, but does not handle errors.
+//
+// ReadTransact makes Snapshot satisfy the ReadTransactor interface, allowing
+// read-only transactional functions to be run with snapshot reads.
+func (s Snapshot) ReadTransact(f func (rtr ReadTransaction) (interface{}, error)) (interface{}, error) {
+	return f(s)
+}
+
 // Like (Transaction).Get(), but as a snapshot read.
 func (s Snapshot) Get(key KeyConvertible) FutureValue {
 	return s.get(key.ToFDBKey(), 1)
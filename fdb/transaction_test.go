@@ -0,0 +1,77 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"testing"
+)
+
+var (
+	_ ReadTransactor = Database{}
+	_ ReadTransactor = Transaction{}
+	_ ReadTransactor = Snapshot{}
+	_ ReadTransaction = Transaction{}
+	_ ReadTransaction = Snapshot{}
+)
+
+func TestTransactionReadTransact(t *testing.T) {
+	tr := Transaction{&transaction{}}
+
+	ret, e := tr.ReadTransact(func(rtr ReadTransaction) (interface{}, error) {
+		if rtr != ReadTransaction(tr) {
+			t.Errorf("ReadTransact passed %#v, want the transaction", rtr)
+		}
+		return 1, nil
+	})
+	if ret != 1 || e != nil {
+		t.Errorf("ReadTransact returned (%v, %v), want (1, <nil>)", ret, e)
+	}
+}
+
+func TestSnapshotReadTransact(t *testing.T) {
+	tr := Transaction{&transaction{}}
+
+	called := false
+	tr.Snapshot().ReadTransact(func(rtr ReadTransaction) (interface{}, error) {
+		called = true
+
+		s, ok := rtr.(Snapshot)
+		if !ok {
+			t.Fatalf("Snapshot().ReadTransact passed a %T, want a Snapshot", rtr)
+		}
+		if s.transaction != tr.transaction {
+			t.Errorf("Snapshot().ReadTransact passed a snapshot of another transaction")
+		}
+
+		/* Writes need a Transaction, which the snapshot cannot be turned into */
+		if _, ok := rtr.(interface{ Set(KeyConvertible, []byte) }); ok {
+			t.Errorf("Snapshot().ReadTransact passed a ReadTransaction that can write")
+		}
+		if _, ok := rtr.(Transactor); ok {
+			t.Errorf("Snapshot().ReadTransact passed a ReadTransaction that is a Transactor")
+		}
+		return nil, nil
+	})
+	if !called {
+		t.Errorf("Snapshot().ReadTransact did not call the function")
+	}
+}
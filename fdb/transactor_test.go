@@ -64,3 +64,19 @@ func ExampleTransactor() {
 	// setOne got:  fdb.Transaction
 	// setOne got:  fdb.Transaction
 }
+
+func ExampleReadTransact() {
+	_ = fdb.APIVersion(100)
+	db, _ := fdb.OpenDefault()
+
+	// The result of the function is returned as a []byte, not an interface{}.
+	value, e := fdb.ReadTransact(db, func (rtr fdb.ReadTransaction) ([]byte, error) {
+		return rtr.Get(fdb.Key("hello")).GetOrPanic(), nil
+	})
+	if e != nil {
+		fmt.Printf("Unable to read hello (%v)\n", e)
+		return
+	}
+
+	fmt.Printf("hello is %q\n", value)
+}
@@ -0,0 +1,204 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+// AccessError describes a tuple element that could not be read as the
+// requested type by one of the Get methods of Tuple, or by UnpackInto.
+type AccessError struct {
+	// Index is the index of the element at fault, or -1 if the error concerns
+	// the tuple as a whole.
+	Index int
+
+	Msg string
+}
+
+func (e *AccessError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("Can't unpack tuple: %s", e.Msg)
+	}
+	return fmt.Sprintf("Can't read tuple element %d: %s", e.Index, e.Msg)
+}
+
+// normalize converts an element of a Tuple built by hand to the type that
+// Unpack would have returned for it.
+func normalize(el interface{}) interface{} {
+	switch el := el.(type) {
+	case int:
+		return int64(el)
+	case uint:
+		return normalize(uint64(el))
+	case uint64:
+		if el <= 1 << 63 - 1 {
+			return int64(el)
+		}
+	case *big.Int:
+		if el.IsInt64() {
+			return el.Int64()
+		}
+		if el.IsUint64() {
+			return el.Uint64()
+		}
+	case fdb.Key:
+		return []byte(el)
+	}
+	return el
+}
+
+// get stores the element at index i in the value pointed to by dst.
+func (t Tuple) get(i int, dst interface{}) error {
+	if i < 0 || i >= len(t) {
+		return &AccessError{i, fmt.Sprintf("index out of range for tuple of length %d", len(t))}
+	}
+	if t[i] == nil {
+		return &AccessError{i, "element is nil"}
+	}
+	if e := unmarshalValue(reflect.ValueOf(dst).Elem(), normalize(t[i])); e != nil {
+		return &AccessError{i, e.Error()}
+	}
+	return nil
+}
+
+// GetInt returns the element at index i as an int64. The element may be an
+// integer of any type whose value fits in an int64.
+func (t Tuple) GetInt(i int) (int64, error) {
+	var v int64
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetUint returns the element at index i as a uint64. The element may be an
+// integer of any type whose value fits in a uint64.
+func (t Tuple) GetUint(i int) (uint64, error) {
+	var v uint64
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetBigInt returns the element at index i, which may be an integer of any
+// type, as a *big.Int.
+func (t Tuple) GetBigInt(i int) (*big.Int, error) {
+	var v *big.Int
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetFloat32 returns the element at index i, which must be a float32, or a
+// float64 that can be represented as a float32 without loss of precision.
+func (t Tuple) GetFloat32(i int) (float32, error) {
+	var v float32
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetFloat64 returns the element at index i, which must be a float32 or
+// float64, as a float64.
+func (t Tuple) GetFloat64(i int) (float64, error) {
+	var v float64
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetString returns the element at index i, which must be a string.
+func (t Tuple) GetString(i int) (string, error) {
+	var v string
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetBytes returns the element at index i, which must be a byte string.
+func (t Tuple) GetBytes(i int) ([]byte, error) {
+	var v []byte
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetBool returns the element at index i, which must be a bool.
+func (t Tuple) GetBool(i int) (bool, error) {
+	var v bool
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetUUID returns the element at index i, which must be a UUID.
+func (t Tuple) GetUUID(i int) (UUID, error) {
+	var v UUID
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetVersionstamp returns the element at index i, which must be a
+// Versionstamp.
+func (t Tuple) GetVersionstamp(i int) (Versionstamp, error) {
+	var v Versionstamp
+	e := t.get(i, &v)
+	return v, e
+}
+
+// GetTuple returns the element at index i, which must be a nested Tuple.
+func (t Tuple) GetTuple(i int) (Tuple, error) {
+	var v Tuple
+	e := t.get(i, &v)
+	return v, e
+}
+
+// UnpackInto unpacks the tuple encoded by b and stores its elements, in order,
+// in the values pointed to by dst. The tuple must have exactly len(dst)
+// elements. A nil destination skips the corresponding element.
+//
+// Each element is converted to its destination with the same rules as
+// Unmarshal: integers may be stored in integer variables of any width that can
+// represent them, nil may only be stored in a pointer or interface, and a
+// nested tuple may be stored in a Tuple or in a struct with tuple tags. Any
+// mismatch is reported as an *AccessError giving the index of the element.
+func UnpackInto(b []byte, dst ...interface{}) error {
+	t, e := Unpack(b)
+	if e != nil {
+		return e
+	}
+
+	if len(t) != len(dst) {
+		return &AccessError{-1, fmt.Sprintf("tuple has %d elements, expected %d", len(t), len(dst))}
+	}
+
+	for i, d := range(dst) {
+		if d == nil {
+			continue
+		}
+		v := reflect.ValueOf(d)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return &AccessError{i, fmt.Sprintf("destination must be a non-nil pointer, not %T", d)}
+		}
+		if e := unmarshalValue(v.Elem(), t[i]); e != nil {
+			return &AccessError{i, e.Error()}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+func TestGetters(t *testing.T) {
+	u := tuple.UUID{0x01}
+	v := tuple.Versionstamp{[10]byte{0x02}, 3}
+	tup := tuple.Tuple{7, uint64(math.MaxUint64), bigInt("-5"), float32(1.5), 2.5, "s", fdb.Key("k"), true, u, v, tuple.Tuple{nil}, nil}
+
+	if i, e := tup.GetInt(0); e != nil || i != 7 {
+		t.Errorf("GetInt(0) = %v, %v", i, e)
+	}
+	if i, e := tup.GetInt(2); e != nil || i != -5 {
+		t.Errorf("GetInt(2) = %v, %v", i, e)
+	}
+	if i, e := tup.GetUint(1); e != nil || i != math.MaxUint64 {
+		t.Errorf("GetUint(1) = %v, %v", i, e)
+	}
+	if b, e := tup.GetBigInt(1); e != nil || b.Cmp(bigInt("18446744073709551615")) != 0 {
+		t.Errorf("GetBigInt(1) = %v, %v", b, e)
+	}
+	if f, e := tup.GetFloat32(3); e != nil || f != 1.5 {
+		t.Errorf("GetFloat32(3) = %v, %v", f, e)
+	}
+	if f, e := tup.GetFloat32(4); e != nil || f != 2.5 {
+		t.Errorf("GetFloat32(4) = %v, %v", f, e)
+	}
+	if f, e := tup.GetFloat64(3); e != nil || f != 1.5 {
+		t.Errorf("GetFloat64(3) = %v, %v", f, e)
+	}
+	if s, e := tup.GetString(5); e != nil || s != "s" {
+		t.Errorf("GetString(5) = %v, %v", s, e)
+	}
+	if b, e := tup.GetBytes(6); e != nil || !bytes.Equal(b, []byte("k")) {
+		t.Errorf("GetBytes(6) = %v, %v", b, e)
+	}
+	if b, e := tup.GetBool(7); e != nil || !b {
+		t.Errorf("GetBool(7) = %v, %v", b, e)
+	}
+	if x, e := tup.GetUUID(8); e != nil || x != u {
+		t.Errorf("GetUUID(8) = %v, %v", x, e)
+	}
+	if x, e := tup.GetVersionstamp(9); e != nil || x != v {
+		t.Errorf("GetVersionstamp(9) = %v, %v", x, e)
+	}
+	if x, e := tup.GetTuple(10); e != nil || len(x) != 1 || x[0] != nil {
+		t.Errorf("GetTuple(10) = %v, %v", x, e)
+	}
+
+	for _, tt := range []struct {
+		name string
+		f func() error
+	}{
+		{"GetInt(1)", func() error { _, e := tup.GetInt(1); return e }},
+		{"GetUint(2)", func() error { _, e := tup.GetUint(2); return e }},
+		{"GetString(0)", func() error { _, e := tup.GetString(0); return e }},
+		{"GetBytes(5)", func() error { _, e := tup.GetBytes(5); return e }},
+		{"GetFloat32(0)", func() error { _, e := tup.GetFloat32(0); return e }},
+		{"GetTuple(11)", func() error { _, e := tup.GetTuple(11); return e }},
+		{"GetBigInt(11)", func() error { _, e := tup.GetBigInt(11); return e }},
+		{"GetBool(12)", func() error { _, e := tup.GetBool(12); return e }},
+		{"GetBool(-1)", func() error { _, e := tup.GetBool(-1); return e }},
+	} {
+		if _, ok := tt.f().(*tuple.AccessError); !ok {
+			t.Errorf("%s did not return an *AccessError", tt.name)
+		}
+	}
+}
+
+func TestUnpackInto(t *testing.T) {
+	b := tuple.Tuple{"user", int64(42), tuple.Tuple{[]byte("x")}, nil, 1.5}.Pack()
+
+	var (
+		s string
+		n uint8
+		nested tuple.Tuple
+		p *int
+		f float32
+	)
+	if e := tuple.UnpackInto(b, &s, &n, &nested, &p, &f); e != nil {
+		t.Fatal(e)
+	}
+	if s != "user" || n != 42 || len(nested) != 1 || p != nil || f != 1.5 {
+		t.Errorf("UnpackInto stored %v, %v, %v, %v, %v", s, n, nested, p, f)
+	}
+
+	if e := tuple.UnpackInto(b, &s, nil, nil, nil, nil); e != nil || s != "user" {
+		t.Errorf("UnpackInto with skipped elements = %v", e)
+	}
+
+	var errTests = []struct {
+		dst []interface{}
+		index int
+	}{
+		{[]interface{}{&s, &n}, -1},
+		{[]interface{}{&n, &n, &nested, &p, &f}, 0},
+		{[]interface{}{&s, &s, &nested, &p, &f}, 1},
+		{[]interface{}{&s, &n, &nested, &s, &f}, 3},
+		{[]interface{}{&s, n, &nested, &p, &f}, 1},
+	}
+	for i, tt := range errTests {
+		e := tuple.UnpackInto(b, tt.dst...)
+		if ae, ok := e.(*tuple.AccessError); !ok || ae.Index != tt.index {
+			t.Errorf("test %d: UnpackInto returned %v, want an *AccessError at index %d", i, e, tt.index)
+		}
+	}
+
+	var small int8
+	e := tuple.UnpackInto(tuple.Tuple{int64(300)}.Pack(), &small)
+	if _, ok := e.(*tuple.AccessError); !ok {
+		t.Errorf("UnpackInto(300, *int8) returned %v, want an *AccessError", e)
+	}
+
+	if _, ok := tuple.UnpackInto([]byte{0x02, 'a'}, &s).(*tuple.DecodeError); !ok {
+		t.Error("UnpackInto of a malformed tuple did not return a *DecodeError")
+	}
+}
@@ -0,0 +1,232 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+// typeOrder returns the typecode that determines where an element sorts
+// relative to elements of other types. All integers share the typecode of
+// zero, as their encodings are ordered numerically across lengths.
+func typeOrder(e interface{}) (byte, bool) {
+	switch e := e.(type) {
+	case nil:
+		return 0x00, true
+	case []byte, fdb.Key:
+		return 0x01, true
+	case string:
+		return 0x02, true
+	case Tuple:
+		return 0x05, true
+	case int64, int, uint64, uint:
+		return 0x14, true
+	case *big.Int:
+		return 0x14, len(e.Bytes()) <= 0xff
+	case float32:
+		return 0x20, true
+	case float64:
+		return 0x21, true
+	case bool:
+		if e {
+			return 0x27, true
+		}
+		return 0x26, true
+	case UUID:
+		return 0x30, true
+	case Versionstamp:
+		return 0x33, true
+	case Desc:
+		_, ok := typeOrder(e.Value)
+		return 0x40, ok
+	}
+	return 0, false
+}
+
+func compareInts(a, b interface{}) int {
+	toInt := func(e interface{}) (int64, bool) {
+		switch e := e.(type) {
+		case int64:
+			return e, true
+		case int:
+			return int64(e), true
+		case uint64:
+			return int64(e), e <= math.MaxInt64
+		case uint:
+			return int64(e), uint64(e) <= math.MaxInt64
+		}
+		return 0, false
+	}
+
+	if ai, ok := toInt(a); ok {
+		if bi, ok := toInt(b); ok {
+			switch {
+			case ai < bi:
+				return -1
+			case ai > bi:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	toBig := func(e interface{}) *big.Int {
+		switch e := e.(type) {
+		case int64:
+			return big.NewInt(e)
+		case int:
+			return big.NewInt(int64(e))
+		case uint64:
+			return new(big.Int).SetUint64(e)
+		case uint:
+			return new(big.Int).SetUint64(uint64(e))
+		}
+		return e.(*big.Int)
+	}
+
+	return toBig(a).Cmp(toBig(b))
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// floatOrder returns the bits of f as adjusted by appendFloat, which order the
+// same way as the encoded bytes.
+func floatOrder(f float32) uint64 {
+	bits := math.Float32bits(f)
+	if bits & (1 << 31) != 0 {
+		return uint64(^bits)
+	}
+	return uint64(bits ^ (1 << 31))
+}
+
+// doubleOrder is floatOrder for a float64.
+func doubleOrder(d float64) uint64 {
+	bits := math.Float64bits(d)
+	if bits & (1 << 63) != 0 {
+		return ^bits
+	}
+	return bits ^ (1 << 63)
+}
+
+func asBytes(e interface{}) []byte {
+	if k, ok := e.(fdb.Key); ok {
+		return []byte(k)
+	}
+	return e.([]byte)
+}
+
+// Compare returns an integer comparing two tuples in the order of their packed
+// encodings: the result is 0 if a.Pack() == b.Pack(), -1 if a.Pack() sorts
+// before b.Pack(), and +1 otherwise. Compare does not pack either tuple, and
+// compares integers of different Go types (int64, int, uint64, uint and
+// *big.Int) by their numerical value. As with Pack, floating point values
+// compare by their encodings, so that -0.0 sorts before 0.0, NaN values sort
+// at the ends, and float32 values sort before all float64 values. Desc elements
+// compare in the reverse order of their values.
+//
+// Compare panics with an *ElementError if it encounters an element that Pack
+// could not encode.
+func Compare(a, b Tuple) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ao, ok := typeOrder(a[i])
+		if !ok {
+			panic(&ElementError{a, i})
+		}
+		bo, ok := typeOrder(b[i])
+		if !ok {
+			panic(&ElementError{b, i})
+		}
+
+		if ao != bo {
+			if ao < bo {
+				return -1
+			}
+			return 1
+		}
+
+		var c int
+
+		switch ae := a[i].(type) {
+		case []byte, fdb.Key:
+			c = bytes.Compare(asBytes(ae), asBytes(b[i]))
+		case string:
+			c = strings.Compare(ae, b[i].(string))
+		case Tuple:
+			c = Compare(ae, b[i].(Tuple))
+		case int64, int, uint64, uint, *big.Int:
+			c = compareInts(ae, b[i])
+		case float32:
+			c = compareUint64(floatOrder(ae), floatOrder(b[i].(float32)))
+		case float64:
+			c = compareUint64(doubleOrder(ae), doubleOrder(b[i].(float64)))
+		case UUID:
+			be := b[i].(UUID)
+			c = bytes.Compare(ae[:], be[:])
+		case Versionstamp:
+			c = bytes.Compare(ae.Bytes(), b[i].(Versionstamp).Bytes())
+		case Desc:
+			c = -Compare(Tuple{ae.Value}, Tuple{b[i].(Desc).Value})
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// TupleSlice attaches the methods of sort.Interface to []Tuple, sorting in
+// increasing order as defined by Compare, which is the order in which the
+// packed tuples would be stored in the database.
+type TupleSlice []Tuple
+
+func (p TupleSlice) Len() int {
+	return len(p)
+}
+
+func (p TupleSlice) Less(i, j int) bool {
+	return Compare(p[i], p[j]) < 0
+}
+
+func (p TupleSlice) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
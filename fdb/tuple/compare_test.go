@@ -0,0 +1,117 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple_test
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+var compareElements = []interface{}{
+	nil,
+	[]byte{}, []byte{0x00}, []byte{0x00, 0x00}, []byte("a"), fdb.Key("a\x00"), []byte("b"),
+	"", "\x00", "a", "a\x00b", "ab", "é",
+	tuple.Tuple{}, tuple.Tuple{nil}, tuple.Tuple{nil, nil}, tuple.Tuple{"a"}, tuple.Tuple{tuple.Tuple{}}, tuple.Tuple{int64(1)},
+	bigInt("-100000000000000000000000"), bigInt("-18446744073709551616"), int64(math.MinInt64), -300, int64(-1),
+	0, uint(0), int64(1), uint64(255), 256, uint64(math.MaxInt64), uint64(math.MaxUint64), bigInt("18446744073709551616"),
+	float32(math.Inf(-1)), float32(-1), float32(math.Copysign(0, -1)), float32(0), float32(1.5), float32(math.NaN()),
+	math.Inf(-1), -2.5, math.Copysign(0, -1), 0.0, 1e-300, 2.5, math.NaN(),
+	false, true,
+	tuple.UUID{}, tuple.UUID{0x01}, tuple.UUID{0xff, 0x00},
+	tuple.Versionstamp{UserVersion: 1}, tuple.Versionstamp{[10]byte{0x01}, 0}, tuple.Versionstamp{[10]byte{0x01}, 2},
+	tuple.Desc{nil}, tuple.Desc{"a"}, tuple.Desc{"ab"}, tuple.Desc{int64(-1)}, tuple.Desc{uint64(math.MaxUint64)},
+	tuple.Desc{tuple.Tuple{nil}}, tuple.Desc{tuple.Desc{int64(3)}},
+}
+
+func sign(c int) int {
+	switch {
+	case c < 0:
+		return -1
+	case c > 0:
+		return 1
+	}
+	return 0
+}
+
+func checkCompare(t *testing.T, a, b tuple.Tuple) {
+	want := bytes.Compare(a.Pack(), b.Pack())
+	if got := tuple.Compare(a, b); sign(got) != want {
+		t.Errorf("Compare(%#v, %#v) = %d, want %d", a, b, got, want)
+	}
+}
+
+func TestCompareElements(t *testing.T) {
+	for _, a := range compareElements {
+		for _, b := range compareElements {
+			checkCompare(t, tuple.Tuple{a}, tuple.Tuple{b})
+			checkCompare(t, tuple.Tuple{a, "x"}, tuple.Tuple{b})
+			checkCompare(t, tuple.Tuple{tuple.Tuple{a}}, tuple.Tuple{tuple.Tuple{b, nil}})
+		}
+	}
+}
+
+func randomTuple(r *rand.Rand, depth int) tuple.Tuple {
+	t := make(tuple.Tuple, r.Intn(4))
+	for i := range(t) {
+		if depth > 0 && r.Intn(8) == 0 {
+			t[i] = randomTuple(r, depth - 1)
+		} else {
+			t[i] = compareElements[r.Intn(len(compareElements))]
+		}
+	}
+	return t
+}
+
+func TestCompareRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		checkCompare(t, randomTuple(r, 2), randomTuple(r, 2))
+	}
+}
+
+func TestTupleSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	ts := make(tuple.TupleSlice, 500)
+	for i := range(ts) {
+		ts[i] = randomTuple(r, 2)
+	}
+	sort.Sort(ts)
+	for i := 1; i < len(ts); i++ {
+		if bytes.Compare(ts[i-1].Pack(), ts[i].Pack()) > 0 {
+			t.Errorf("%#v sorted before %#v", ts[i-1], ts[i])
+		}
+	}
+}
+
+func TestCompareUnsupported(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(*tuple.ElementError); !ok {
+			t.Error("Compare did not panic with an *ElementError")
+		}
+	}()
+	tuple.Compare(tuple.Tuple{int64(1)}, tuple.Tuple{struct{}{}})
+}
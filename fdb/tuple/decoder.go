@@ -0,0 +1,501 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+// ElementType identifies the type of an element of a packed tuple.
+type ElementType int
+
+const (
+	// NilType is the type of nil elements.
+	NilType ElementType = iota
+
+	// BytesType is the type of byte string elements, unpacked as []byte.
+	BytesType
+
+	// StringType is the type of unicode string elements, unpacked as string.
+	StringType
+
+	// TupleType is the type of nested tuple elements, unpacked as Tuple.
+	TupleType
+
+	// IntType is the type of integer elements, unpacked as int64, uint64 or
+	// *big.Int.
+	IntType
+
+	// FloatType is the type of single-precision floating point elements,
+	// unpacked as float32.
+	FloatType
+
+	// DoubleType is the type of double-precision floating point elements,
+	// unpacked as float64.
+	DoubleType
+
+	// BoolType is the type of boolean elements, unpacked as bool.
+	BoolType
+
+	// UUIDType is the type of UUID elements, unpacked as UUID.
+	UUIDType
+
+	// VersionstampType is the type of versionstamp elements, unpacked as
+	// Versionstamp.
+	VersionstampType
+
+	// DescType is the type of descending-order elements, unpacked as Desc.
+	DescType
+)
+
+var elementTypeNames = []string{"nil", "bytes", "string", "tuple", "int", "float", "double", "bool", "UUID", "versionstamp", "desc"}
+
+func (t ElementType) String() string {
+	if t < 0 || int(t) >= len(elementTypeNames) {
+		return fmt.Sprintf("ElementType(%d)", int(t))
+	}
+	return elementTypeNames[t]
+}
+
+// elementSize returns the length and type of the complete element at the start
+// of b, without decoding it. Within a nested tuple (where nested is true), nil
+// is encoded as 0x00 0xff. Each byte of b is read XORed with mask, which is 0xff
+// within the inverted encoding wrapped by a Desc. The Offset of a returned error
+// is relative to b.
+func elementSize(b []byte, nested bool, mask byte) (int, ElementType, *DecodeError) {
+	var n int
+	var t ElementType
+
+	code := b[0] ^ mask
+
+	switch {
+	case code == 0x00:
+		if !nested {
+			return 1, NilType, nil
+		}
+		if len(b) < 2 || b[1] ^ mask != 0xff {
+			return 0, 0, &DecodeError{0, code, "unexpected terminator"}
+		}
+		return 2, NilType, nil
+	case code == 0x01 || code == 0x02:
+		idx := findTerminator(b[1:], mask)
+		if idx < 0 {
+			return 0, 0, &DecodeError{0, code, errUnterminated.Error()}
+		}
+		t = BytesType
+		if code == 0x02 {
+			t = StringType
+		}
+		return idx + 2, t, nil
+	case code == 0x05:
+		i := 1
+		for {
+			if i >= len(b) {
+				return 0, 0, &DecodeError{0, code, errUnterminated.Error()}
+			}
+			if b[i] ^ mask == 0x00 {
+				if i + 1 < len(b) && b[i+1] ^ mask == 0xff {
+					i += 2
+					continue
+				}
+				return i + 1, TupleType, nil
+			}
+			en, _, e := elementSize(b[i:], true, mask)
+			if e != nil {
+				e.Offset += i
+				return 0, 0, e
+			}
+			i += en
+		}
+	case code == 0x0b || code == 0x1d:
+		if len(b) < 2 {
+			return 0, 0, &DecodeError{0, code, errTruncated.Error()}
+		}
+		n = int(b[1] ^ mask)
+		if code == 0x0b {
+			n ^= 0xff
+		}
+		n, t = n + 2, IntType
+	case code == 0x0c:
+		n, t = 9, IntType
+	case 0x0d <= code && code <= 0x1c:
+		n = int(code) - 0x14
+		if n < 0 {
+			n = -n
+		}
+		n, t = n + 1, IntType
+	case code == 0x20:
+		n, t = 5, FloatType
+	case code == 0x21:
+		n, t = 9, DoubleType
+	case code == 0x26 || code == 0x27:
+		n, t = 1, BoolType
+	case code == 0x30:
+		n, t = 17, UUIDType
+	case code == 0x33:
+		n, t = 13, VersionstampType
+	case code == 0x40:
+		if len(b) < 2 {
+			return 0, 0, &DecodeError{0, code, errTruncated.Error()}
+		}
+		n, _, e := elementSize(b[1:], false, ^mask)
+		if e != nil {
+			e.Offset += 1
+			return 0, 0, e
+		}
+		if n + 1 == len(b) || b[n+1] ^ mask != 0xff {
+			return 0, 0, &DecodeError{0, code, errUnterminated.Error()}
+		}
+		return n + 2, DescType, nil
+	default:
+		return 0, 0, &DecodeError{0, code, "unknown typecode"}
+	}
+
+	if len(b) < n {
+		return 0, 0, &DecodeError{0, code, errTruncated.Error()}
+	}
+
+	return n, t, nil
+}
+
+// Decoder walks the elements of a packed tuple one at a time, without unpacking
+// the whole tuple. Elements are only decoded when one of the accessor methods is
+// called for them, so skipping over an element costs no more than finding its
+// length, and the typed accessors return values without boxing them in an
+// interface{}.
+//
+// A Decoder is created with NewDecoder and advanced with Next:
+//
+//     d := tuple.NewDecoder(key)
+//     for d.Next() {
+//         switch d.Type() {
+//         ...
+//         }
+//     }
+//     if e := d.Err(); e != nil {
+//         ...
+//     }
+//
+// A Decoder must not be used concurrently by multiple goroutines.
+type Decoder struct {
+	b []byte
+	base int
+	nested bool
+
+	next int
+	pos int
+	raw []byte
+	typ ElementType
+	err error
+}
+
+// NewDecoder returns a Decoder positioned before the first element of the
+// tuple packed in b. The Decoder does not copy b, which must not be modified
+// while the Decoder is in use.
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{b: b}
+}
+
+// Next advances the Decoder to the next element of the tuple, returning false
+// when there are no more elements or a malformed element is found. After Next
+// returns false, Err reports any error that occurred.
+func (d *Decoder) Next() bool {
+	d.raw = nil
+
+	if d.err != nil || d.next >= len(d.b) {
+		return false
+	}
+
+	n, t, e := elementSize(d.b[d.next:], d.nested, 0)
+	if e != nil {
+		e.Offset += d.base + d.next
+		d.err = e
+		return false
+	}
+
+	d.pos = d.next
+	d.raw = d.b[d.next:d.next+n:d.next+n]
+	d.typ = t
+	d.next += n
+
+	return true
+}
+
+// Last advances the Decoder to the final element of the tuple, returning false
+// if there are no further elements or a malformed element is found.
+func (d *Decoder) Last() bool {
+	if !d.Next() {
+		return false
+	}
+	for {
+		pos, raw, typ := d.pos, d.raw, d.typ
+		if !d.Next() {
+			if d.err != nil {
+				return false
+			}
+			d.pos, d.raw, d.typ = pos, raw, typ
+			return true
+		}
+	}
+}
+
+// Err returns the first error encountered by the Decoder, which will be a
+// *DecodeError, or nil if the tuple has been well-formed so far.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Type returns the type of the current element.
+func (d *Decoder) Type() ElementType {
+	return d.typ
+}
+
+// Offset returns the position of the current element within the packed tuple.
+// For a Decoder returned by Nested, the position is within the outermost
+// packed tuple.
+func (d *Decoder) Offset() int {
+	return d.base + d.pos
+}
+
+// Raw returns the encoding of the current element, including its typecode. The
+// returned slice refers to the packed tuple, and is nil if the Decoder is not
+// positioned on an element.
+func (d *Decoder) Raw() []byte {
+	return d.raw
+}
+
+func (d *Decoder) check(t ElementType) error {
+	if d.raw == nil {
+		return fmt.Errorf("Decoder is not positioned on an element")
+	}
+	if d.typ != t {
+		return fmt.Errorf("Tuple element at offset %d is %s, not %s", d.Offset(), d.typ, t)
+	}
+	return nil
+}
+
+// Value returns the current element as Unpack would represent it.
+func (d *Decoder) Value() (interface{}, error) {
+	if d.raw == nil {
+		return nil, fmt.Errorf("Decoder is not positioned on an element")
+	}
+
+	var el interface{}
+	var e error
+
+	switch d.typ {
+	case NilType:
+		return nil, nil
+	case TupleType:
+		el, _, e = decodeTuple(d.raw, 1, true, 0)
+	case BoolType:
+		return d.raw[0] == 0x27, nil
+	default:
+		el, e = d.decode()
+	}
+
+	if e != nil {
+		// Unreachable, as Next has already checked the element.
+		return nil, &DecodeError{d.Offset(), d.raw[0], e.Error()}
+	}
+
+	return el, nil
+}
+
+func (d *Decoder) decode() (el interface{}, e error) {
+	switch d.typ {
+	case BytesType:
+		el, _, e = decodeBytes(d.raw)
+	case StringType:
+		el, _, e = decodeString(d.raw)
+	case IntType:
+		if code := d.raw[0]; code == 0x0b || code == 0x0c || code == 0x1d {
+			el, _, e = decodeBigInt(d.raw)
+		} else {
+			el, _, e = decodeInt(d.raw)
+		}
+	case FloatType:
+		el, _, e = decodeFloat(d.raw)
+	case DoubleType:
+		el, _, e = decodeDouble(d.raw)
+	case UUIDType:
+		el, _, e = decodeUUID(d.raw)
+	case VersionstampType:
+		el, _, e = decodeVersionstamp(d.raw)
+	case DescType:
+		el, _, e = decodeDesc(d.raw)
+	}
+	return
+}
+
+// IsNil reports whether the current element is nil.
+func (d *Decoder) IsNil() bool {
+	return d.raw != nil && d.typ == NilType
+}
+
+// Bytes returns the current element, which must be a byte string. If the
+// element contains no escaped 0x00 bytes, the returned slice refers to the
+// packed tuple rather than a copy.
+func (d *Decoder) Bytes() ([]byte, error) {
+	if e := d.check(BytesType); e != nil {
+		return nil, e
+	}
+	body := d.raw[1:len(d.raw)-1]
+	if bytes.IndexByte(body, 0x00) < 0 {
+		return body, nil
+	}
+	b, _, _ := decodeBytes(d.raw)
+	return b, nil
+}
+
+// Text returns the current element, which must be a unicode string.
+func (d *Decoder) Text() (string, error) {
+	if e := d.check(StringType); e != nil {
+		return "", e
+	}
+	s, _, _ := decodeString(d.raw)
+	return s, nil
+}
+
+// Int64 returns the current element, which must be an integer that fits in an
+// int64.
+func (d *Decoder) Int64() (int64, error) {
+	if e := d.check(IntType); e != nil {
+		return 0, e
+	}
+
+	if code := d.raw[0]; 0x0d <= code && code <= 0x1c {
+		if i, ok := decodeInt64(d.raw); ok {
+			return i, nil
+		}
+	} else {
+		v, _, _ := decodeBigInt(d.raw)
+		if i, ok := v.(int64); ok {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Tuple element at offset %d overflows int64", d.Offset())
+}
+
+// Uint64 returns the current element, which must be a non-negative integer
+// that fits in a uint64.
+func (d *Decoder) Uint64() (uint64, error) {
+	if e := d.check(IntType); e != nil {
+		return 0, e
+	}
+
+	if code := d.raw[0]; 0x0d <= code && code <= 0x1c {
+		if i, ok := decodeInt64(d.raw); !ok || i >= 0 {
+			return uint64(i), nil
+		}
+	} else {
+		switch v, _, _ := decodeBigInt(d.raw); v := v.(type) {
+		case int64:
+			if v >= 0 {
+				return uint64(v), nil
+			}
+		case uint64:
+			return v, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Tuple element at offset %d overflows uint64", d.Offset())
+}
+
+// BigInt returns the current element, which must be an integer, as a *big.Int.
+func (d *Decoder) BigInt() (*big.Int, error) {
+	if e := d.check(IntType); e != nil {
+		return nil, e
+	}
+
+	v, _ := d.decode()
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	}
+	return v.(*big.Int), nil
+}
+
+// Float32 returns the current element, which must be a single-precision
+// floating point number.
+func (d *Decoder) Float32() (float32, error) {
+	if e := d.check(FloatType); e != nil {
+		return 0, e
+	}
+	f, _, _ := decodeFloat(d.raw)
+	return f, nil
+}
+
+// Float64 returns the current element, which must be a single- or
+// double-precision floating point number.
+func (d *Decoder) Float64() (float64, error) {
+	if d.raw != nil && d.typ == FloatType {
+		f, _, _ := decodeFloat(d.raw)
+		return float64(f), nil
+	}
+	if e := d.check(DoubleType); e != nil {
+		return 0, e
+	}
+	f, _, _ := decodeDouble(d.raw)
+	return f, nil
+}
+
+// Bool returns the current element, which must be a boolean.
+func (d *Decoder) Bool() (bool, error) {
+	if e := d.check(BoolType); e != nil {
+		return false, e
+	}
+	return d.raw[0] == 0x27, nil
+}
+
+// UUID returns the current element, which must be a UUID.
+func (d *Decoder) UUID() (UUID, error) {
+	if e := d.check(UUIDType); e != nil {
+		return UUID{}, e
+	}
+	u, _, _ := decodeUUID(d.raw)
+	return u, nil
+}
+
+// Versionstamp returns the current element, which must be a versionstamp.
+func (d *Decoder) Versionstamp() (Versionstamp, error) {
+	if e := d.check(VersionstampType); e != nil {
+		return Versionstamp{}, e
+	}
+	v, _, _ := decodeVersionstamp(d.raw)
+	return v, nil
+}
+
+// Nested returns a Decoder over the elements of the current element, which
+// must be a nested tuple.
+func (d *Decoder) Nested() (*Decoder, error) {
+	if e := d.check(TupleType); e != nil {
+		return nil, e
+	}
+	return &Decoder{b: d.raw[1:len(d.raw)-1], base: d.Offset() + 1, nested: true}, nil
+}
@@ -0,0 +1,154 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+func TestDecoderValues(t *testing.T) {
+	for _, tt := range packTests {
+		enc, _ := hex.DecodeString(tt.enc)
+
+		var got tuple.Tuple
+		d := tuple.NewDecoder(enc)
+		for d.Next() {
+			v, e := d.Value()
+			if e != nil {
+				t.Fatalf("Value() at offset %d of %s returned error: %v", d.Offset(), tt.enc, e)
+			}
+			got = append(got, v)
+		}
+		if e := d.Err(); e != nil {
+			t.Errorf("Decoder over %s returned error: %v", tt.enc, e)
+		}
+		if !reflect.DeepEqual(got, tt.t) {
+			t.Errorf("Decoder over %s = %#v, want %#v", tt.enc, got, tt.t)
+		}
+	}
+}
+
+func TestDecoderMalformed(t *testing.T) {
+	for _, tt := range malformedTests {
+		enc, _ := hex.DecodeString(tt.enc)
+
+		d := tuple.NewDecoder(enc)
+		for d.Next() {
+		}
+		de, ok := d.Err().(*tuple.DecodeError)
+		if !ok {
+			t.Errorf("Decoder over %s returned %v, want *DecodeError", tt.enc, d.Err())
+			continue
+		}
+		if de.Offset != tt.offset || de.Code != tt.code {
+			t.Errorf("Decoder over %s failed at offset %d (code %02x), want offset %d (code %02x)", tt.enc, de.Offset, de.Code, tt.offset, tt.code)
+		}
+	}
+}
+
+func TestDecoderAccessors(t *testing.T) {
+	p := tuple.Tuple{"users", tuple.Tuple{nil, []byte("a\x00b"), int64(-5)}, uint64(1 << 63), int64(77)}.Pack()
+
+	d := tuple.NewDecoder(p)
+	if !d.Next() || d.Type() != tuple.StringType {
+		t.Fatalf("first element is %v", d.Type())
+	}
+	if s, e := d.Text(); s != "users" || e != nil {
+		t.Errorf("Text() = %q, %v", s, e)
+	}
+	if _, e := d.Int64(); e == nil {
+		t.Error("Int64() of a string element returned no error")
+	}
+
+	if !d.Next() || d.Type() != tuple.TupleType {
+		t.Fatalf("second element is %v", d.Type())
+	}
+	nd, e := d.Nested()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !nd.Next() || !nd.IsNil() {
+		t.Errorf("first nested element is %v, want nil", nd.Type())
+	}
+	if !nd.Next() {
+		t.Fatal(nd.Err())
+	}
+	if b, e := nd.Bytes(); string(b) != "a\x00b" || e != nil {
+		t.Errorf("Bytes() = %q, %v", b, e)
+	}
+	if !nd.Next() {
+		t.Fatal(nd.Err())
+	}
+	if i, e := nd.Int64(); i != -5 || e != nil {
+		t.Errorf("Int64() = %d, %v", i, e)
+	}
+	if nd.Next() {
+		t.Errorf("nested Decoder has unexpected element %v", nd.Raw())
+	}
+
+	if !d.Next() {
+		t.Fatal(d.Err())
+	}
+	if _, e := d.Int64(); e == nil {
+		t.Error("Int64() of 2^63 returned no error")
+	}
+	if u, e := d.Uint64(); u != 1 << 63 || e != nil {
+		t.Errorf("Uint64() = %d, %v", u, e)
+	}
+
+	d = tuple.NewDecoder(p)
+	if !d.Last() {
+		t.Fatal(d.Err())
+	}
+	if i, e := d.Int64(); i != 77 || e != nil {
+		t.Errorf("Int64() of last element = %d, %v", i, e)
+	}
+}
+
+func BenchmarkDecoderLast(b *testing.B) {
+	p := benchTuple.Pack()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := tuple.NewDecoder(p)
+		d.Last()
+		d.Float64()
+	}
+}
+
+func ExampleDecoder() {
+	key := tuple.Tuple{"scores", "alice", int64(1234)}.Pack()
+
+	// Read only the last element of the key, without unpacking the others.
+	d := tuple.NewDecoder(key)
+	if d.Last() {
+		score, _ := d.Int64()
+		fmt.Println(score)
+	}
+
+	// Output:
+	// 1234
+}
@@ -0,0 +1,398 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+var (
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+	uuidType = reflect.TypeOf(UUID{})
+	versionstampType = reflect.TypeOf(Versionstamp{})
+	tupleType = reflect.TypeOf(Tuple{})
+	keyType = reflect.TypeOf(fdb.Key{})
+	descType = reflect.TypeOf(Desc{})
+)
+
+// MarshalError describes a Go value that could not be mapped to or from a
+// tuple by Marshal or Unmarshal.
+type MarshalError struct {
+	// Type is the struct type being marshaled or unmarshaled.
+	Type reflect.Type
+
+	// Field is the name of the struct field at fault, or empty if the error
+	// concerns the struct as a whole.
+	Field string
+
+	// Index is the tuple index of Field, or -1 if Field is empty.
+	Index int
+
+	Msg string
+}
+
+func (e *MarshalError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("Cannot map %s to a tuple: %s", e.Type, e.Msg)
+	}
+	return fmt.Sprintf("Cannot map field %s (index %d) of %s to a tuple: %s", e.Field, e.Index, e.Type, e.Msg)
+}
+
+// tupleFields returns the indices of the struct fields of t that carry a tuple
+// tag, ordered by the tuple index named in the tag.
+func tupleFields(t reflect.Type) ([]int, error) {
+	byPos := make(map[int]int)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, ok := f.Tag.Lookup("tuple")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		n, e := strconv.Atoi(tag)
+		if e != nil || n < 0 {
+			return nil, &MarshalError{t, f.Name, -1, fmt.Sprintf("invalid tuple tag %q", tag)}
+		}
+		if f.PkgPath != "" {
+			return nil, &MarshalError{t, f.Name, n, "field is not exported"}
+		}
+		if prev, ok := byPos[n]; ok {
+			return nil, &MarshalError{t, f.Name, n, fmt.Sprintf("index is already used by field %s", t.Field(prev).Name)}
+		}
+
+		byPos[n] = i
+	}
+
+	fields := make([]int, len(byPos))
+	for n, i := range(byPos) {
+		if n >= len(fields) {
+			return nil, &MarshalError{t, "", -1, fmt.Sprintf("tuple tags must number fields consecutively from 0 (found %d with %d tagged fields)", n, len(fields))}
+		}
+		fields[n] = i
+	}
+
+	return fields, nil
+}
+
+// errNoTupleFields returns an error if the struct type t has no tagged fields.
+// A field of such a type, such as time.Time, would otherwise be silently
+// encoded as an empty tuple.
+func errNoTupleFields(t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		if tag, ok := t.Field(i).Tag.Lookup("tuple"); ok && tag != "-" {
+			return nil
+		}
+	}
+	return fmt.Errorf("struct type %s has no fields tagged with a tuple index", t)
+}
+
+func marshalStruct(v reflect.Value) (Tuple, error) {
+	fields, e := tupleFields(v.Type())
+	if e != nil {
+		return nil, e
+	}
+
+	t := make(Tuple, len(fields))
+
+	for n, i := range(fields) {
+		el, e := marshalValue(v.Field(i))
+		if e != nil {
+			if _, ok := e.(*MarshalError); !ok {
+				e = &MarshalError{v.Type(), v.Type().Field(i).Name, n, e.Error()}
+			}
+			return nil, e
+		}
+		t[n] = el
+	}
+
+	return t, nil
+}
+
+func marshalValue(v reflect.Value) (interface{}, error) {
+	switch v.Type() {
+	case bigIntType:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface(), nil
+	case uuidType, versionstampType, tupleType, keyType, descType:
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32:
+		return float32(v.Float()), nil
+	case reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+	case reflect.Struct:
+		if e := errNoTupleFields(v.Type()); e != nil {
+			return nil, e
+		}
+		return marshalStruct(v)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return marshalValue(v.Elem())
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// Marshal returns the packed tuple encoding of v, which must be a struct or a
+// pointer to a struct.
+//
+// Only exported fields tagged with a tuple index, such as `tuple:"0"`, are
+// encoded. The tagged fields must be numbered consecutively from 0, and are
+// encoded as the elements of the tuple in that order. Fields of struct type
+// (other than Versionstamp and Desc) are encoded as nested tuples, and nil
+// pointers as nil elements. The struct type of such a field must itself have
+// tagged fields; a field of a struct type without them, such as time.Time, is
+// reported as a *MarshalError rather than encoded as an empty tuple. Signed
+// and unsigned integers of any width are encoded as integers, and []byte and
+// fdb.Key fields as byte strings.
+func Marshal(v interface{}) (ret []byte, e error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct || rv.Type() == versionstampType || rv.Type() == descType {
+		return nil, fmt.Errorf("Marshal requires a struct or pointer to struct, not %T", v)
+	}
+
+	t, e := marshalStruct(rv)
+	if e != nil {
+		return nil, e
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if ee, ok := r.(*ElementError); ok {
+				e = ee
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	return t.Pack(), nil
+}
+
+func unmarshalStruct(v reflect.Value, t Tuple) error {
+	fields, e := tupleFields(v.Type())
+	if e != nil {
+		return e
+	}
+
+	if len(t) != len(fields) {
+		return &MarshalError{v.Type(), "", -1, fmt.Sprintf("tuple has %d elements, expected %d", len(t), len(fields))}
+	}
+
+	for n, i := range(fields) {
+		if e := unmarshalValue(v.Field(i), t[n]); e != nil {
+			if _, ok := e.(*MarshalError); !ok {
+				e = &MarshalError{v.Type(), v.Type().Field(i).Name, n, e.Error()}
+			}
+			return e
+		}
+	}
+
+	return nil
+}
+
+func mismatch(el interface{}, v reflect.Value) error {
+	if el == nil {
+		return fmt.Errorf("cannot store nil in %s", v.Type())
+	}
+	return fmt.Errorf("cannot store %T (%v) in %s", el, el, v.Type())
+}
+
+// unmarshalValue stores the tuple element el in v, converting between numeric
+// widths where this can be done without loss.
+func unmarshalValue(v reflect.Value, el interface{}) error {
+	switch v.Type() {
+	case bigIntType:
+		switch el := el.(type) {
+		case nil:
+			v.Set(reflect.Zero(bigIntType))
+		case int64:
+			v.Set(reflect.ValueOf(big.NewInt(el)))
+		case uint64:
+			v.Set(reflect.ValueOf(new(big.Int).SetUint64(el)))
+		case *big.Int:
+			v.Set(reflect.ValueOf(el))
+		default:
+			return mismatch(el, v)
+		}
+		return nil
+	case keyType:
+		if b, ok := el.([]byte); ok {
+			v.SetBytes(b)
+			return nil
+		}
+		return mismatch(el, v)
+	case uuidType, versionstampType, tupleType, descType:
+		if el != nil && reflect.TypeOf(el) == v.Type() {
+			v.Set(reflect.ValueOf(el))
+			return nil
+		}
+		return mismatch(el, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if el == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if reflect.TypeOf(el).AssignableTo(v.Type()) {
+			v.Set(reflect.ValueOf(el))
+			return nil
+		}
+	case reflect.Ptr:
+		if el == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if e := unmarshalValue(p.Elem(), el); e != nil {
+			return e
+		}
+		v.Set(p)
+		return nil
+	case reflect.Bool:
+		if b, ok := el.(bool); ok {
+			v.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch el := el.(type) {
+		case int64:
+			i = el
+		case uint64, *big.Int:
+			return fmt.Errorf("value %v overflows %s", el, v.Type())
+		default:
+			return mismatch(el, v)
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch el := el.(type) {
+		case int64:
+			if el < 0 {
+				return fmt.Errorf("value %d overflows %s", el, v.Type())
+			}
+			u = uint64(el)
+		case uint64:
+			u = el
+		case *big.Int:
+			return fmt.Errorf("value %v overflows %s", el, v.Type())
+		default:
+			return mismatch(el, v)
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, v.Type())
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch el := el.(type) {
+		case float32:
+			v.SetFloat(float64(el))
+			return nil
+		case float64:
+			// Narrowing is only allowed when no precision is lost.
+			if v.Kind() == reflect.Float64 || float64(float32(el)) == el || el != el {
+				v.SetFloat(el)
+				return nil
+			}
+			return fmt.Errorf("value %v cannot be stored in %s without loss of precision", el, v.Type())
+		}
+	case reflect.String:
+		if s, ok := el.(string); ok {
+			v.SetString(s)
+			return nil
+		}
+	case reflect.Slice:
+		if b, ok := el.([]byte); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(b)
+			return nil
+		}
+	case reflect.Struct:
+		if e := errNoTupleFields(v.Type()); e != nil {
+			return e
+		}
+		if t, ok := el.(Tuple); ok {
+			return unmarshalStruct(v, t)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return mismatch(el, v)
+}
+
+// Unmarshal unpacks the tuple encoded by b and stores its elements in the
+// struct pointed to by v, following the same field tags as Marshal.
+//
+// The tuple must have exactly as many elements as v has tagged fields. Integer
+// elements may be stored in integer fields of any width that can represent
+// them, and in *big.Int fields. A nil element may only be stored in a pointer
+// or interface field. Any other mismatch between an element and its field is
+// reported as a *MarshalError.
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct || rv.Elem().Type() == versionstampType || rv.Elem().Type() == descType {
+		return fmt.Errorf("Unmarshal requires a non-nil pointer to a struct, not %T", v)
+	}
+
+	t, e := Unpack(b)
+	if e != nil {
+		return e
+	}
+
+	return unmarshalStruct(rv.Elem(), t)
+}
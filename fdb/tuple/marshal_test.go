@@ -0,0 +1,133 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple_test
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+type point struct {
+	X float64 `tuple:"0"`
+	Y float64 `tuple:"1"`
+}
+
+type record struct {
+	Name string `tuple:"1"`
+	ID int64 `tuple:"0"`
+	Score int16 `tuple:"2"`
+	Count uint32 `tuple:"3"`
+	Data []byte `tuple:"4"`
+	Where point `tuple:"5"`
+	Parent *point `tuple:"6"`
+	Ledger *big.Int `tuple:"7"`
+	ignored string
+	Untagged string
+}
+
+func TestMarshal(t *testing.T) {
+	r := record{
+		Name: "alice",
+		ID: 42,
+		Score: -7,
+		Count: 9,
+		Data: []byte{0x00, 0x01},
+		Where: point{1.5, -2.5},
+		Ledger: big.NewInt(12),
+		Untagged: "not encoded",
+	}
+
+	b, e := tuple.Marshal(&r)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := tuple.Tuple{int64(42), "alice", int64(-7), uint64(9), []byte{0x00, 0x01}, tuple.Tuple{1.5, -2.5}, nil, int64(12)}.Pack()
+	if !bytes.Equal(b, want) {
+		t.Errorf("Marshal() = %x, want %x", b, want)
+	}
+
+	var out record
+	if e := tuple.Unmarshal(b, &out); e != nil {
+		t.Fatal(e)
+	}
+	r.Untagged = ""
+	if !reflect.DeepEqual(out, r) {
+		t.Errorf("Unmarshal() = %+v, want %+v", out, r)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		t tuple.Tuple
+		field string
+	}{
+		{tuple.Tuple{"x", 1.0}, "X"},
+		{tuple.Tuple{1.0, nil}, "Y"},
+		{tuple.Tuple{1.0}, ""},
+	}
+
+	for _, tt := range tests {
+		var p point
+		e := tuple.Unmarshal(tt.t.Pack(), &p)
+		me, ok := e.(*tuple.MarshalError)
+		if !ok {
+			t.Errorf("Unmarshal(%v) returned %v, want *MarshalError", tt.t, e)
+			continue
+		}
+		if me.Field != tt.field {
+			t.Errorf("Unmarshal(%v) failed on field %q, want %q", tt.t, me.Field, tt.field)
+		}
+	}
+
+	var narrow struct {
+		N int8 `tuple:"0"`
+	}
+	if e := tuple.Unmarshal(tuple.Tuple{int64(300)}.Pack(), &narrow); e == nil {
+		t.Error("Unmarshal of 300 into int8 returned no error")
+	}
+
+	var gap struct {
+		A int `tuple:"0"`
+		B int `tuple:"2"`
+	}
+	if _, e := tuple.Marshal(gap); e == nil {
+		t.Error("Marshal with non-consecutive tags returned no error")
+	}
+
+	var untaggedStruct struct {
+		When time.Time `tuple:"0"`
+	}
+	_, e := tuple.Marshal(untaggedStruct)
+	if me, ok := e.(*tuple.MarshalError); !ok || me.Field != "When" {
+		t.Errorf("Marshal of a field of untagged struct type returned %v, want *MarshalError for When", e)
+	}
+	e = tuple.Unmarshal(tuple.Tuple{tuple.Tuple{}}.Pack(), &untaggedStruct)
+	if me, ok := e.(*tuple.MarshalError); !ok || me.Field != "When" {
+		t.Errorf("Unmarshal into a field of untagged struct type returned %v, want *MarshalError for When", e)
+	}
+}
@@ -0,0 +1,432 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tuple
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+// appendQuotedBytes writes b as a double-quoted literal in which every byte
+// outside printable ASCII is escaped as \xNN.
+func appendQuotedBytes(buf *bytes.Buffer, b []byte) {
+	const hexDigits = "0123456789abcdef"
+
+	buf.WriteByte('"')
+	for _, c := range(b) {
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			buf.WriteByte(c)
+		default:
+			buf.WriteString(`\x`)
+			buf.WriteByte(hexDigits[c >> 4])
+			buf.WriteByte(hexDigits[c & 0xf])
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// formatFloat formats f so that it always reads back as a floating point
+// number rather than an integer.
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func writeElement(buf *bytes.Buffer, e interface{}) {
+	switch e := e.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case []byte:
+		buf.WriteByte('b')
+		appendQuotedBytes(buf, e)
+	case fdb.Key:
+		buf.WriteByte('b')
+		appendQuotedBytes(buf, []byte(e))
+	case string:
+		buf.WriteString(strconv.Quote(e))
+	case Tuple:
+		writeTuple(buf, e)
+	case int64:
+		buf.WriteString(strconv.FormatInt(e, 10))
+	case int:
+		buf.WriteString(strconv.Itoa(e))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(e, 10))
+	case uint:
+		buf.WriteString(strconv.FormatUint(uint64(e), 10))
+	case *big.Int:
+		buf.WriteString(e.String())
+	case float32:
+		buf.WriteString("float32(")
+		buf.WriteString(formatFloat(float64(e), 32))
+		buf.WriteByte(')')
+	case float64:
+		buf.WriteString(formatFloat(e, 64))
+	case bool:
+		buf.WriteString(strconv.FormatBool(e))
+	case UUID:
+		buf.WriteString("UUID(")
+		buf.WriteString(e.String())
+		buf.WriteByte(')')
+	case Versionstamp:
+		buf.WriteString(e.String())
+	case Desc:
+		buf.WriteString("Desc(")
+		writeElement(buf, e.Value)
+		buf.WriteByte(')')
+	default:
+		fmt.Fprintf(buf, "%#v", e)
+	}
+}
+
+func writeTuple(buf *bytes.Buffer, t Tuple) {
+	buf.WriteByte('(')
+	for i, e := range(t) {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeElement(buf, e)
+	}
+	buf.WriteByte(')')
+}
+
+// String returns a human-readable representation of the Tuple, such as
+// ("abc", b"\x00", 12, nil), which can be read back with Parse.
+//
+// Strings are written as double-quoted Go string literals, and byte strings as
+// double-quoted literals prefixed by b in which bytes outside printable ASCII
+// are escaped as \xNN. Integers of every type are written in decimal, float64
+// values always include a decimal point or exponent, and float32 values are
+// written as float32(1.5). UUIDs are written as UUID(8-4-4-4-12), and
+// Versionstamps as Versionstamp(<20 hex digits>, <user version>). Desc
+// elements are written as Desc(<element>), and nested tuples in parentheses.
+func (t Tuple) String() string {
+	var buf bytes.Buffer
+	writeTuple(&buf, t)
+	return buf.String()
+}
+
+// ParseError describes a position in the input to Parse that is not valid
+// tuple text.
+type ParseError struct {
+	// Offset is the byte offset within the input at which the error was
+	// detected.
+	Offset int
+
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Can't parse tuple at offset %d: %s", e.Offset, e.Msg)
+}
+
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &ParseError{p.i, fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// consume skips leading whitespace, and then the byte c if it is next.
+func (p *parser) consume(c byte) bool {
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(c byte) error {
+	if !p.consume(c) {
+		if p.i == len(p.s) {
+			return p.errorf("expected %q, found end of input", c)
+		}
+		return p.errorf("expected %q, found %q", c, p.s[p.i])
+	}
+	return nil
+}
+
+// token returns the run of bytes starting at the current position that may
+// form a word or number.
+func (p *parser) token() string {
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || strings.IndexByte("+-._", c) >= 0) {
+			break
+		}
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// quoted reads a double-quoted literal and returns its unescaped contents.
+func (p *parser) quoted() (string, error) {
+	start := p.i
+	p.i++
+	for p.i < len(p.s) && p.s[p.i] != '"' {
+		if p.s[p.i] == '\\' {
+			p.i++
+		}
+		p.i++
+	}
+	if p.i >= len(p.s) {
+		p.i = start
+		return "", p.errorf("unterminated string literal")
+	}
+	p.i++
+
+	s, e := strconv.Unquote(p.s[start:p.i])
+	if e != nil {
+		p.i = start
+		return "", p.errorf("invalid string literal %s", p.s[start:p.i])
+	}
+	return s, nil
+}
+
+// wrapped reads the parenthesized argument of a form such as UUID(...).
+func (p *parser) wrapped() (string, error) {
+	if e := p.expect('('); e != nil {
+		return "", e
+	}
+	start := p.i
+	end := strings.IndexByte(p.s[start:], ')')
+	if end < 0 {
+		return "", p.errorf("missing ')'")
+	}
+	p.i = start + end + 1
+	return strings.TrimSpace(p.s[start:start + end]), nil
+}
+
+func parseFloat(s string, bitSize int) (float64, error) {
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, bitSize)
+}
+
+// parseInt returns the integer represented by s as the smallest of int64,
+// uint64 and *big.Int that can hold it, as Unpack would.
+func parseInt(s string) (interface{}, bool) {
+	if i, e := strconv.ParseInt(s, 10, 64); e == nil {
+		return i, true
+	}
+	if u, e := strconv.ParseUint(strings.TrimPrefix(s, "+"), 10, 64); e == nil {
+		return u, true
+	}
+	if b, ok := new(big.Int).SetString(s, 10); ok {
+		return b, true
+	}
+	return nil, false
+}
+
+func (p *parser) element() (interface{}, error) {
+	p.skipSpace()
+	if p.i == len(p.s) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	start := p.i
+
+	switch c := p.s[p.i]; {
+	case c == '(':
+		return p.tuple()
+	case c == '"':
+		return p.quoted()
+	case c == 'b' && p.i + 1 < len(p.s) && p.s[p.i+1] == '"':
+		p.i++
+		s, e := p.quoted()
+		if e != nil {
+			return nil, e
+		}
+		return []byte(s), nil
+	}
+
+	tok := p.token()
+	switch tok {
+	case "":
+		return nil, p.errorf("unexpected %q", p.s[p.i])
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "float32":
+		arg, e := p.wrapped()
+		if e != nil {
+			return nil, e
+		}
+		f, e := parseFloat(arg, 32)
+		if e != nil {
+			p.i = start
+			return nil, p.errorf("invalid float32 %q", arg)
+		}
+		return float32(f), nil
+	case "UUID":
+		arg, e := p.wrapped()
+		if e != nil {
+			return nil, e
+		}
+		var u UUID
+		b, e := hex.DecodeString(strings.Replace(arg, "-", "", -1))
+		if e != nil || len(b) != len(u) {
+			p.i = start
+			return nil, p.errorf("invalid UUID %q", arg)
+		}
+		copy(u[:], b)
+		return u, nil
+	case "Desc":
+		if e := p.expect('('); e != nil {
+			return nil, e
+		}
+		el, e := p.element()
+		if e != nil {
+			return nil, e
+		}
+		if e := p.expect(')'); e != nil {
+			return nil, e
+		}
+		return Desc{el}, nil
+	case "Versionstamp":
+		arg, e := p.wrapped()
+		if e != nil {
+			return nil, e
+		}
+		var v Versionstamp
+		parts := strings.Split(arg, ",")
+		if len(parts) == 2 {
+			tv, e1 := hex.DecodeString(strings.TrimSpace(parts[0]))
+			uv, e2 := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 16)
+			if e1 == nil && e2 == nil && len(tv) == len(v.TransactionVersion) {
+				copy(v.TransactionVersion[:], tv)
+				v.UserVersion = uint16(uv)
+				return v, nil
+			}
+		}
+		p.i = start
+		return nil, p.errorf("invalid Versionstamp %q", arg)
+	}
+
+	if strings.ContainsAny(tok, ".eEIN") {
+		if f, e := parseFloat(tok, 64); e == nil {
+			return f, nil
+		}
+	} else if i, ok := parseInt(tok); ok {
+		return i, nil
+	}
+
+	p.i = start
+	return nil, p.errorf("invalid element %q", tok)
+}
+
+func (p *parser) tuple() (Tuple, error) {
+	if e := p.expect('('); e != nil {
+		return nil, e
+	}
+
+	t := Tuple{}
+
+	if p.consume(')') {
+		return t, nil
+	}
+
+	for {
+		el, e := p.element()
+		if e != nil {
+			return nil, e
+		}
+		t = append(t, el)
+
+		if p.consume(')') {
+			return t, nil
+		}
+		if e := p.expect(','); e != nil {
+			return nil, e
+		}
+		// A trailing comma is allowed, as in ("abc",)
+		if p.consume(')') {
+			return t, nil
+		}
+	}
+}
+
+// Parse reads a tuple in the notation produced by Tuple.String, such as
+// ("abc", b"\x00", 12, nil). Whitespace between elements is ignored, and a
+// trailing comma is permitted. Integers are returned as the smallest of int64,
+// uint64 and *big.Int that can represent them, as Unpack does. The special
+// float values are written NaN, +Inf and -Inf; since NaN has many encodings,
+// only the NaN returned by math.NaN() is produced.
+//
+// If s is not valid tuple text, Parse returns a *ParseError.
+func Parse(s string) (Tuple, error) {
+	p := &parser{s: s}
+
+	t, e := p.tuple()
+	if e != nil {
+		return nil, e
+	}
+
+	p.skipSpace()
+	if p.i != len(p.s) {
+		return nil, p.errorf("unexpected %q after tuple", p.s[p.i])
+	}
+
+	return t, nil
+}
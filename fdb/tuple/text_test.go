@@ -0,0 +1,122 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple_test
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+var stringTests = []struct {
+	t tuple.Tuple
+	s string
+}{
+	{tuple.Tuple{}, "()"},
+	{tuple.Tuple{"abc", []byte{0x00}, int64(12), nil}, `("abc", b"\x00", 12, nil)`},
+	{tuple.Tuple{fdb.Key("a\"\\\xff"), "é\x00"}, `(b"a\"\\\xff", "é\x00")`},
+	{tuple.Tuple{int64(-5), uint64(math.MaxUint64), bigInt("-18446744073709551616")}, "(-5, 18446744073709551615, -18446744073709551616)"},
+	{tuple.Tuple{1.0, -2.5e-10, math.Inf(-1), float32(1.5), float32(math.Inf(1))}, "(1.0, -2.5e-10, -Inf, float32(1.5), float32(+Inf))"},
+	{tuple.Tuple{true, false}, "(true, false)"},
+	{tuple.Tuple{tuple.UUID{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}}, "(UUID(12345678-9abc-def0-0123-456789abcdef))"},
+	{tuple.Tuple{tuple.IncompleteVersionstamp(3)}, "(Versionstamp(ffffffffffffffffffff, 3))"},
+	{tuple.Tuple{tuple.Tuple{}, tuple.Tuple{"a", tuple.Tuple{nil}}}, `((), ("a", (nil)))`},
+}
+
+func TestString(t *testing.T) {
+	for _, tt := range stringTests {
+		if s := tt.t.String(); s != tt.s {
+			t.Errorf("String() = %s, want %s", s, tt.s)
+		}
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	var tuples []tuple.Tuple
+	for _, tt := range packTests {
+		tuples = append(tuples, tt.t)
+	}
+	for _, e := range compareElements {
+		tuples = append(tuples, tuple.Tuple{e, tuple.Tuple{e}})
+	}
+
+	for _, tup := range tuples {
+		p, e := tuple.Parse(tup.String())
+		if e != nil {
+			t.Errorf("Parse(%s) failed: %v", tup, e)
+			continue
+		}
+		if !bytes.Equal(p.Pack(), tup.Pack()) {
+			t.Errorf("Parse(%s) = %#v, want %#v", tup, p, tup)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, e := tuple.Parse(" ( 1 ,b\"x\",\t1e3, 18446744073709551616, \"\\u00e9\",) ")
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := tuple.Tuple{int64(1), []byte("x"), 1000.0, new(big.Int).Lsh(big.NewInt(1), 64), "é"}
+	if !bytes.Equal(p.Pack(), want.Pack()) {
+		t.Errorf("Parse = %#v, want %#v", p, want)
+	}
+	if _, ok := p[3].(*big.Int); !ok {
+		t.Errorf("Parse returned %T for a large integer, want *big.Int", p[3])
+	}
+}
+
+var parseErrorTests = []struct {
+	s string
+	offset int
+}{
+	{"", 0},
+	{"1", 0},
+	{"(1", 2},
+	{"(1 2)", 3},
+	{`("abc)`, 1},
+	{`(b"\q")`, 2},
+	{"(foo)", 1},
+	{"(1.2.3)", 1},
+	{"(UUID(1234))", 1},
+	{"(Versionstamp(00, 1))", 1},
+	{"(float32(x))", 1},
+	{"() ()", 3},
+	{"(,)", 1},
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range parseErrorTests {
+		_, e := tuple.Parse(tt.s)
+		pe, ok := e.(*tuple.ParseError)
+		if !ok {
+			t.Errorf("Parse(%q) returned %v, want a *ParseError", tt.s, e)
+			continue
+		}
+		if pe.Offset != tt.offset {
+			t.Errorf("Parse(%q) failed at offset %d, want %d (%v)", tt.s, pe.Offset, tt.offset, e)
+		}
+	}
+}
@@ -25,27 +25,114 @@
 // second element, etc. This makes the tuple layer ideal for building a variety
 // of higher-level data models.
 //
-// FoundationDB tuple's can currently encode byte and unicode strings, integers
-// and NULL values. In Go these are represented as []byte, string, int64 (or
-// int) and nil.
+// FoundationDB tuple's can currently encode byte and unicode strings, integers,
+// floating point numbers, booleans, UUIDs, versionstamps, nested tuples and NULL
+// values. In Go these are represented as []byte, string, int64 (or int, uint64,
+// uint and *big.Int), float32, float64, bool, UUID, Versionstamp, Tuple and nil.
+//
+// Integers are unpacked as the smallest of int64, uint64 and *big.Int that can
+// represent the encoded value.
+//
+// Any element may be wrapped in Desc to make it sort in descending order within
+// an otherwise ascending tuple. Desc elements use the typecode 0x40, from the
+// range reserved for application-specific types, and cannot be unpacked by the
+// tuple layers of other language bindings.
 package tuple
 
 import (
 	"fmt"
+	"errors"
 	"encoding/binary"
 	"bytes"
+	"math"
+	"math/big"
+	"strings"
 	"github.com/FoundationDB/fdb-go/fdb"
 )
 
 // Tuple is a slice of objects that can be encoded as FoundationDB tuples. If a
-// tuple contains elements of types other than []byte, string, int64, int or
-// nil, an error will be returned when the Tuple is packed.
+// tuple contains elements of types other than []byte, string, int64, int,
+// uint64, uint, *big.Int, float32, float64, bool, UUID, Versionstamp, Desc,
+// Tuple or nil, an error will be returned when the Tuple is packed.
 //
 // Given a Tuple T containing objects only of these types, then T will be
 // identical to the Tuple returned by unpacking the byte slice obtained by
 // packing T.
 type Tuple []interface{}
 
+// UUID wraps a basic byte array as a UUID. We do not provide any special
+// methods for accessing or generating the UUID, but as Go does not provide
+// a built-in UUID type, this simple wrapper allows for other libraries
+// to write the output of their UUID type as a 16-byte array into
+// an instance of this type.
+type UUID [16]byte
+
+// String returns the UUID in its canonical 8-4-4-4-12 hexadecimal form.
+func (uuid UUID) String() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// Versionstamp is a 12-byte value made up of the 10-byte version at which a
+// transaction was committed, followed by a 2-byte user version that
+// distinguishes multiple versionstamps written by a single transaction.
+// Versionstamps are unique across the lifetime of a database and increase
+// monotonically with commit order.
+//
+// A Versionstamp whose TransactionVersion has not yet been assigned by the
+// database is incomplete (see IncompleteVersionstamp). Tuples containing an
+// incomplete Versionstamp must be packed with PackWithVersionstamp.
+type Versionstamp struct {
+	TransactionVersion [10]byte
+	UserVersion uint16
+}
+
+var incompleteTransactionVersion = [10]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// IncompleteVersionstamp returns a Versionstamp with the provided user version
+// whose transaction version will be filled in by the database when the
+// transaction that writes it is committed.
+func IncompleteVersionstamp(userVersion uint16) Versionstamp {
+	return Versionstamp{incompleteTransactionVersion, userVersion}
+}
+
+// IsComplete returns false if the transaction version of the Versionstamp has
+// not yet been assigned by the database.
+func (v Versionstamp) IsComplete() bool {
+	return v.TransactionVersion != incompleteTransactionVersion
+}
+
+// Bytes returns the 12-byte serialized form of the Versionstamp.
+func (v Versionstamp) Bytes() []byte {
+	b := make([]byte, 12)
+	copy(b, v.TransactionVersion[:])
+	binary.BigEndian.PutUint16(b[10:], v.UserVersion)
+	return b
+}
+
+// String returns a human-readable representation of the Versionstamp.
+func (v Versionstamp) String() string {
+	return fmt.Sprintf("Versionstamp(%x, %d)", v.TransactionVersion[:], v.UserVersion)
+}
+
+// Desc wraps a tuple element so that it sorts in descending order relative to
+// other Desc elements in the same position, while the rest of the tuple still
+// sorts in ascending order. For example, the keys packed from
+// Tuple{"scores", Desc{score}, name} list the highest scores first in a
+// forward range read.
+//
+// A Desc is encoded as the typecode 0x40, followed by the bitwise inversion of
+// the encoding of Value, followed by 0xff. Inverting the encoding reverses its
+// order, and the trailing 0xff keeps the order reversed when one encoding is a
+// prefix of another (as the encoding of "a" is of the encoding of "a\x00"). All
+// Desc elements sort after all elements of other types. Unpack returns a
+// Desc for each Desc element it decodes.
+//
+// Value may be of any type that a Tuple can contain other than an incomplete
+// Versionstamp, as the database could not fill it in.
+type Desc struct {
+	Value interface{}
+}
+
 var sizeLimits = []uint64{
 	1 << (0 * 8) - 1,
 	1 << (1 * 8) - 1,
@@ -58,10 +145,37 @@ var sizeLimits = []uint64{
 	1 << (8 * 8) - 1,
 }
 
-func encodeBytes(buf *bytes.Buffer, code byte, b []byte) {
-	buf.WriteByte(code)
-	buf.Write(bytes.Replace(b, []byte{0x00}, []byte{0x00, 0xff}, -1))
-	buf.WriteByte(0x00)
+// appendBytes appends b to dst with the given type code, escaping 0x00 bytes as
+// 0x00 0xff and adding a 0x00 terminator.
+func appendBytes(dst []byte, code byte, b []byte) []byte {
+	dst = append(dst, code)
+	for {
+		idx := bytes.IndexByte(b, 0x00)
+		if idx < 0 {
+			break
+		}
+		dst = append(dst, b[:idx+1]...)
+		dst = append(dst, 0xff)
+		b = b[idx+1:]
+	}
+	dst = append(dst, b...)
+	return append(dst, 0x00)
+}
+
+// appendString is appendBytes for a string, without first converting it.
+func appendString(dst []byte, code byte, s string) []byte {
+	dst = append(dst, code)
+	for {
+		idx := strings.IndexByte(s, 0x00)
+		if idx < 0 {
+			break
+		}
+		dst = append(dst, s[:idx+1]...)
+		dst = append(dst, 0xff)
+		s = s[idx+1:]
+	}
+	dst = append(dst, s...)
+	return append(dst, 0x00)
 }
 
 func bisectLeft(u uint64) int {
@@ -72,27 +186,87 @@ func bisectLeft(u uint64) int {
 	return n
 }
 
-func encodeInt(buf *bytes.Buffer, i int64) {
-	if i == 0 {
-		buf.WriteByte(0x14)
-		return
+// appendBigEndian appends the low n bytes of u, most significant first.
+func appendBigEndian(dst []byte, u uint64, n int) []byte {
+	for j := n - 1; j >= 0; j-- {
+		dst = append(dst, byte(u >> uint(8*j)))
 	}
+	return dst
+}
 
-	var n int
-	var ibuf bytes.Buffer
+func appendUint(dst []byte, u uint64) []byte {
+	n := bisectLeft(u)
+	dst = append(dst, byte(0x14+n))
+	return appendBigEndian(dst, u, n)
+}
 
-	switch {
-	case i > 0:
-		n = bisectLeft(uint64(i))
-		buf.WriteByte(byte(0x14+n))
-		binary.Write(&ibuf, binary.BigEndian, i)
-	case i < 0:
-		n = bisectLeft(uint64(-i))
-		buf.WriteByte(byte(0x14-n))
-		binary.Write(&ibuf, binary.BigEndian, int64(sizeLimits[n])+i)
+func appendInt(dst []byte, i int64) []byte {
+	if i >= 0 {
+		return appendUint(dst, uint64(i))
 	}
 
-	buf.Write(ibuf.Bytes()[8-n:])
+	// uint64(-i) is correct even for math.MinInt64, and adding sizeLimits[n]
+	// (modulo 2^64) yields the one's complement of the absolute value.
+	n := bisectLeft(uint64(-i))
+	dst = append(dst, byte(0x14-n))
+	return appendBigEndian(dst, uint64(i) + sizeLimits[n], n)
+}
+
+// appendBigInt encodes integers of up to 255 bytes. Values that fit in 8 bytes
+// share the encoding of int64 and uint64; longer values are written with the
+// 0x1d (positive) or 0x0b (negative) type code followed by a length byte.
+func appendBigInt(dst []byte, i *big.Int) []byte {
+	n := len(i.Bytes())
+
+	if i.Sign() >= 0 {
+		if n > 8 {
+			dst = append(dst, 0x1d, byte(n))
+		} else {
+			dst = append(dst, byte(0x14+n))
+		}
+		return append(dst, i.Bytes()...)
+	}
+
+	// Negative values are stored as i + (2^(8n) - 1), that is, as the one's
+	// complement of their absolute value.
+	add := new(big.Int).Lsh(big.NewInt(1), uint(n*8))
+	add.Sub(add, big.NewInt(1))
+	ib := new(big.Int).Add(i, add).Bytes()
+
+	if n > 8 {
+		dst = append(dst, 0x0b, byte(n) ^ 0xff)
+	} else {
+		dst = append(dst, byte(0x14-n))
+	}
+
+	// Bytes() drops leading zeros, which are significant here.
+	for j := len(ib); j < n; j++ {
+		dst = append(dst, 0x00)
+	}
+	return append(dst, ib...)
+}
+
+// appendFloat stores f as its big-endian IEEE 754 representation, adjusted to
+// sort bytewise in numerical order: negative numbers have all of their bits
+// flipped, while positive numbers have only their sign bit flipped.
+func appendFloat(dst []byte, f float32) []byte {
+	bits := math.Float32bits(f)
+	if bits & (1 << 31) != 0 {
+		bits = ^bits
+	} else {
+		bits ^= 1 << 31
+	}
+	return appendBigEndian(append(dst, 0x20), uint64(bits), 4)
+}
+
+func appendDouble(dst []byte, d float64) []byte {
+	bits := math.Float64bits(d)
+	if bits & (1 << 63) != 0 {
+		bits = ^bits
+	} else {
+		bits ^= 1 << 63
+	}
+	return appendBigEndian(append(dst, 0x21), bits, 8)
 }
 
 type ElementError struct {
@@ -104,42 +278,194 @@ func (e *ElementError) Error() string {
 	return fmt.Sprintf("Unencodable element at index %d (%v, type %T)", e.Index, e.Tuple[e.Index], e.Tuple[e.Index])
 }
 
-// Pack returns a byte slice encoding the provided tuple. Pack will panic if the
-// tuple contains an element of any type other than []byte, string, int64, int
-// or nil.
-func (t Tuple) Pack() []byte {
-	buf := new(bytes.Buffer)
+// encodeTuple appends the encoding of t to dst. If vsPos is non-nil, the
+// offset within dst of an incomplete Versionstamp is stored there; otherwise,
+// an incomplete Versionstamp is treated as unencodable.
+func encodeTuple(dst []byte, t Tuple, nested bool, vsPos *int) []byte {
+	if nested {
+		dst = append(dst, 0x05)
+	}
 
 	for i, e := range(t) {
 		switch e := e.(type) {
+		case Tuple:
+			dst = encodeTuple(dst, e, true, vsPos)
 		case nil:
-			buf.WriteByte(0x00)
+			dst = append(dst, 0x00)
+			if nested {
+				// Inside a nested tuple a bare 0x00 is the terminator, so
+				// nil elements are escaped.
+				dst = append(dst, 0xff)
+			}
 		case int64:
-			encodeInt(buf, e)
+			dst = appendInt(dst, e)
 		case int:
-			encodeInt(buf, int64(e))
+			dst = appendInt(dst, int64(e))
+		case uint64:
+			dst = appendUint(dst, e)
+		case uint:
+			dst = appendUint(dst, uint64(e))
+		case *big.Int:
+			if e == nil || len(e.Bytes()) > 0xff {
+				panic(&ElementError{t, i})
+			}
+			dst = appendBigInt(dst, e)
 		case []byte:
-			encodeBytes(buf, 0x01, e)
+			dst = appendBytes(dst, 0x01, e)
 		case fdb.Key:
-			encodeBytes(buf, 0x01, []byte(e))
+			dst = appendBytes(dst, 0x01, []byte(e))
 		case string:
-			encodeBytes(buf, 0x02, []byte(e))
+			dst = appendString(dst, 0x02, e)
+		case float32:
+			dst = appendFloat(dst, e)
+		case float64:
+			dst = appendDouble(dst, e)
+		case bool:
+			if e {
+				dst = append(dst, 0x27)
+			} else {
+				dst = append(dst, 0x26)
+			}
+		case UUID:
+			dst = append(dst, 0x30)
+			dst = append(dst, e[:]...)
+		case Versionstamp:
+			dst = append(dst, 0x33)
+			if !e.IsComplete() {
+				if vsPos == nil {
+					panic(&ElementError{t, i})
+				}
+				*vsPos = len(dst)
+			}
+			dst = append(dst, e.TransactionVersion[:]...)
+			dst = append(dst, byte(e.UserVersion >> 8), byte(e.UserVersion))
+		case Desc:
+			dst = append(dst, 0x40)
+			start := len(dst)
+			dst = encodeTuple(dst, Tuple{e.Value}, false, nil)
+			for j := start; j < len(dst); j++ {
+				dst[j] = ^dst[j]
+			}
+			dst = append(dst, 0xff)
 		default:
 			panic(&ElementError{t, i})
 		}
 	}
 
-	return buf.Bytes()
+	if nested {
+		dst = append(dst, 0x00)
+	}
+
+	return dst
+}
+
+// Pack returns a byte slice encoding the provided tuple. Pack will panic if the
+// tuple contains an element of any type other than []byte, string, int64, int,
+// uint64, uint, *big.Int, float32, float64, bool, UUID, Versionstamp, Desc,
+// Tuple or nil, if it contains a *big.Int whose magnitude does not fit in 255
+// bytes, or if it contains an incomplete Versionstamp.
+func (t Tuple) Pack() []byte {
+	return encodeTuple(nil, t, false, nil)
+}
+
+// PackTo appends the encoding of the provided tuple to dst and returns the
+// extended slice, in the manner of the built-in append. If dst has sufficient
+// capacity, PackTo does not allocate. PackTo panics under the same conditions
+// as Pack.
+func (t Tuple) PackTo(dst []byte) []byte {
+	return encodeTuple(dst, t, false, nil)
+}
+
+// Encoder packs tuples into a buffer that is reused from one call to the next,
+// so that once the buffer has grown to fit the tuples being packed, packing
+// does not allocate. The zero value of Encoder is ready to use. An Encoder
+// must not be used concurrently by multiple goroutines.
+type Encoder struct {
+	buf []byte
+}
+
+// Pack returns a byte slice encoding the provided tuple, and panics under the
+// same conditions as (Tuple).Pack. The returned slice refers to the Encoder's
+// buffer, and is only valid until the next call to Pack; callers that retain it
+// must make a copy. (Passing it to a Transaction method, which copies its
+// arguments, is safe.)
+func (enc *Encoder) Pack(t Tuple) []byte {
+	enc.buf = encodeTuple(enc.buf[:0], t, false, nil)
+	return enc.buf
+}
+
+func countIncompleteVersionstamps(t Tuple) int {
+	var n int
+
+	for _, e := range(t) {
+		switch e := e.(type) {
+		case Versionstamp:
+			if !e.IsComplete() {
+				n += 1
+			}
+		case Tuple:
+			n += countIncompleteVersionstamps(e)
+		}
+	}
+
+	return n
+}
+
+// PackWithVersionstamp returns a byte slice encoding the provided tuple, along
+// with the byte offset within it of the 10-byte transaction version of its
+// incomplete Versionstamp. The database will overwrite those 10 bytes with the
+// commit version when the packed tuple is used in a versionstamped mutation.
+//
+// PackWithVersionstamp returns an error if the tuple (including any nested
+// tuples) does not contain exactly one incomplete Versionstamp, and panics
+// under the same conditions as Pack otherwise.
+func (t Tuple) PackWithVersionstamp() ([]byte, int, error) {
+	if n := countIncompleteVersionstamps(t); n != 1 {
+		return nil, 0, fmt.Errorf("Tuple must contain exactly one incomplete versionstamp (found %d)", n)
+	}
+
+	var vsPos int
+
+	p := encodeTuple(nil, t, false, &vsPos)
+
+	return p, vsPos, nil
+}
+
+// DecodeError describes a malformed element found while unpacking a tuple.
+type DecodeError struct {
+	// Offset is the position within the packed tuple of the first byte (the
+	// type code) of the element that could not be decoded.
+	Offset int
+
+	// Code is the type code of the element that could not be decoded.
+	Code byte
+
+	Msg string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Can't decode tuple element at offset %d (typecode %02x): %s", e.Offset, e.Code, e.Msg)
 }
 
-func findTerminator(b []byte) int {
+var (
+	errTruncated = errors.New("element is truncated")
+	errUnterminated = errors.New("missing terminator")
+)
+
+// findTerminator returns the index in b of the 0x00 byte that terminates an
+// escaped byte string, or -1 if there is none. Each byte of b is read XORed with
+// mask, so that the inverted encoding inside a Desc can be scanned in place.
+func findTerminator(b []byte, mask byte) int {
 	bp := b
 	var length int
 
 	for {
-		idx := bytes.IndexByte(bp, 0x00)
+		idx := bytes.IndexByte(bp, mask)
+		if idx < 0 {
+			return -1
+		}
 		length += idx
-		if idx + 1 == len(bp) || bp[idx+1] != 0xff {
+		if idx + 1 == len(bp) || bp[idx+1] ^ mask != 0xff {
 			break
 		}
 		length += 2
@@ -149,79 +475,287 @@ func findTerminator(b []byte) int {
 	return length
 }
 
-func decodeBytes(b []byte) ([]byte, int) {
-	idx := findTerminator(b[1:])
-	return bytes.Replace(b[1:idx+1], []byte{0x00, 0xff}, []byte{0x00}, -1), idx + 2
+func decodeBytes(b []byte) ([]byte, int, error) {
+	idx := findTerminator(b[1:], 0)
+	if idx < 0 {
+		return nil, 0, errUnterminated
+	}
+	return bytes.Replace(b[1:idx+1], []byte{0x00, 0xff}, []byte{0x00}, -1), idx + 2, nil
 }
 
-func decodeString(b []byte) (string, int) {
-	bp, idx := decodeBytes(b)
-	return string(bp), idx
+func decodeString(b []byte) (string, int, error) {
+	bp, idx, e := decodeBytes(b)
+	return string(bp), idx, e
 }
 
-func decodeInt(b []byte) (int64, int) {
-	if b[0] == 0x14 {
-		return 0, 1
+// decodeInt64 decodes an integer of at most 8 bytes (typecodes 0x0d to 0x1c)
+// from b, which must hold exactly the complete element. A positive 8-byte value
+// with its high bit set overflows int64; it is returned reinterpreted as an
+// int64 with ok set to false, and will always fit in a uint64.
+func decodeInt64(b []byte) (i int64, ok bool) {
+	var u uint64
+	for _, c := range(b[1:]) {
+		u = u << 8 | uint64(c)
 	}
 
-	var neg bool
+	if b[0] < 0x14 {
+		// 8-byte negative integers are handled by decodeBigInt, so this
+		// cannot overflow.
+		return int64(u - sizeLimits[len(b)-1]), true
+	}
+
+	return int64(u), u <= math.MaxInt64
+}
 
-	n := int(b[0]) - 20
+func decodeInt(b []byte) (interface{}, int, error) {
+	n := int(b[0]) - 0x14
 	if n < 0 {
 		n = -n
-		neg = true
 	}
 
-	bp := make([]byte, 8)
-	copy(bp[8-n:], b[1:n+1])
+	if len(b) < n+1 {
+		return nil, 0, errTruncated
+	}
+
+	i, ok := decodeInt64(b[:n+1])
+	if !ok {
+		return uint64(i), n+1, nil
+	}
+
+	return i, n+1, nil
+}
+
+// decodeBigInt decodes the variable-length 0x0b and 0x1d integer encodings, as
+// well as 8-byte negative integers (0x0c), which may not fit in an int64.
+func decodeBigInt(b []byte) (interface{}, int, error) {
+	var n int
+	off := 1
+
+	switch b[0] {
+	case 0x0b, 0x1d:
+		if len(b) < 2 {
+			return nil, 0, errTruncated
+		}
+		n = int(b[1])
+		if b[0] == 0x0b {
+			n ^= 0xff
+		}
+		off += 1
+	default:
+		n = 8
+	}
+
+	if len(b) < off+n {
+		return nil, 0, errTruncated
+	}
 
-	var ret int64
+	ret := new(big.Int).SetBytes(b[off:off+n])
 
-	binary.Read(bytes.NewBuffer(bp), binary.BigEndian, &ret)
+	if b[0] < 0x14 {
+		sub := new(big.Int).Lsh(big.NewInt(1), uint(n*8))
+		sub.Sub(sub, big.NewInt(1))
+		ret.Sub(ret, sub)
+	}
 
-	if neg {
-		ret -= int64(sizeLimits[n])
+	if ret.IsInt64() {
+		return ret.Int64(), off+n, nil
+	}
+	if ret.IsUint64() {
+		return ret.Uint64(), off+n, nil
 	}
 
-	return ret, n+1
+	return ret, off+n, nil
 }
 
-// Unpack returns the tuple encoded by the provided byte slice, or an error if
-// the byte slice did not correctly encode a FoundationDB tuple.
-func Unpack(b []byte) (Tuple, error) {
-	var t Tuple
+func decodeFloat(b []byte) (float32, int, error) {
+	if len(b) < 5 {
+		return 0, 0, errTruncated
+	}
+	bits := binary.BigEndian.Uint32(b[1:5])
+	if bits & (1 << 31) != 0 {
+		bits ^= 1 << 31
+	} else {
+		bits = ^bits
+	}
+	return math.Float32frombits(bits), 5, nil
+}
 
-	var i int
+func decodeDouble(b []byte) (float64, int, error) {
+	if len(b) < 9 {
+		return 0, 0, errTruncated
+	}
+	bits := binary.BigEndian.Uint64(b[1:9])
+	if bits & (1 << 63) != 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits), 9, nil
+}
+
+func decodeUUID(b []byte) (UUID, int, error) {
+	var u UUID
+	if len(b) < 17 {
+		return u, 0, errTruncated
+	}
+	copy(u[:], b[1:])
+	return u, 17, nil
+}
+
+func decodeVersionstamp(b []byte) (Versionstamp, int, error) {
+	var v Versionstamp
+	if len(b) < 13 {
+		return v, 0, errTruncated
+	}
+	copy(v.TransactionVersion[:], b[1:11])
+	v.UserVersion = binary.BigEndian.Uint16(b[11:13])
+	return v, 13, nil
+}
+
+func decodeDesc(b []byte) (Desc, int, error) {
+	el, n, e := decodeElement(b, 0, false, 0)
+	if e != nil {
+		return Desc{}, 0, e
+	}
+	return el.(Desc), n, nil
+}
+
+// decodeElement decodes the element at offset i of b, returning it along with
+// its length. Each byte of b is read XORed with mask, which is 0xff within the
+// inverted encoding wrapped by a Desc, so nested Descs are decoded in a single
+// pass over b. Any error is a *DecodeError with an Offset relative to b.
+func decodeElement(b []byte, i int, nested bool, mask byte) (interface{}, int, error) {
+	code := b[i] ^ mask
+
+	switch code {
+	case 0x05:
+		t, end, e := decodeTuple(b, i + 1, true, mask)
+		if e != nil {
+			if de, ok := e.(*DecodeError); ok {
+				return nil, 0, de
+			}
+			return nil, 0, &DecodeError{i, code, e.Error()}
+		}
+		return t, end - i, nil
+	case 0x40:
+		if i + 1 == len(b) {
+			return nil, 0, &DecodeError{i, code, errTruncated.Error()}
+		}
+		el, n, e := decodeElement(b, i + 1, false, ^mask)
+		if e != nil {
+			return nil, 0, e
+		}
+		end := i + 1 + n
+		if end == len(b) || b[end] ^ mask != 0xff {
+			return nil, 0, &DecodeError{i, code, errUnterminated.Error()}
+		}
+		return Desc{el}, end + 1 - i, nil
+	}
+
+	raw := b[i:]
+	if mask != 0 {
+		// Any other element is decoded from an uninverted copy of just its
+		// own bytes.
+		n, _, de := elementSize(raw, nested, mask)
+		if de != nil {
+			de.Offset += i
+			return nil, 0, de
+		}
+		raw = make([]byte, n)
+		for j := range(raw) {
+			raw[j] = b[i+j] ^ mask
+		}
+	}
+
+	var el interface{}
+	var off int
+	var e error
+
+	switch {
+	case code == 0x00 && nested:
+		off = 2
+	case code == 0x00:
+		off = 1
+	case code == 0x01:
+		el, off, e = decodeBytes(raw)
+	case code == 0x02:
+		el, off, e = decodeString(raw)
+	case code == 0x0b || code == 0x0c || code == 0x1d:
+		el, off, e = decodeBigInt(raw)
+	case 0x0d <= code && code <= 0x1c:
+		el, off, e = decodeInt(raw)
+	case code == 0x20:
+		el, off, e = decodeFloat(raw)
+	case code == 0x21:
+		el, off, e = decodeDouble(raw)
+	case code == 0x26:
+		el, off = false, 1
+	case code == 0x27:
+		el, off = true, 1
+	case code == 0x30:
+		el, off, e = decodeUUID(raw)
+	case code == 0x33:
+		el, off, e = decodeVersionstamp(raw)
+	default:
+		e = errors.New("unknown typecode")
+	}
+
+	if e != nil {
+		return nil, 0, &DecodeError{i, code, e.Error()}
+	}
+
+	return el, off, nil
+}
+
+// decodeTuple decodes the elements of b beginning at offset i, reading each byte
+// XORed with mask as decodeElement does. A nested tuple is decoded up to its
+// terminator, and the offset following the terminator is returned; otherwise
+// all of b is decoded.
+func decodeTuple(b []byte, i int, nested bool, mask byte) (Tuple, int, error) {
+	var t Tuple
+	if nested {
+		t = Tuple{}
+	}
 
 	for i < len(b) {
-		var el interface{}
-		var off int
-
-		switch {
-		case b[i] == 0x00:
-			el = nil
-			off = 1
-		case b[i] == 0x01:
-			el, off = decodeBytes(b[i:])
-		case b[i] == 0x02:
-			el, off = decodeString(b[i:])
-		case 0x0c <= b[i] && b[i] <= 0x1c:
-			el, off = decodeInt(b[i:])
-		default:
-			return nil, fmt.Errorf("Can't decode tuple typecode %02x", b[i])
+		if nested && b[i] ^ mask == 0x00 && (i + 1 == len(b) || b[i+1] ^ mask != 0xff) {
+			return t, i + 1, nil
+		}
+
+		el, off, e := decodeElement(b, i, nested, mask)
+		if e != nil {
+			return nil, 0, e
 		}
 
 		t = append(t, el)
 		i += off
 	}
 
+	if nested {
+		return nil, 0, errUnterminated
+	}
+
+	return t, i, nil
+}
+
+// Unpack returns the tuple encoded by the provided byte slice, or an error if
+// the byte slice did not correctly encode a FoundationDB tuple. Any error
+// describing malformed input is a *DecodeError. Unpack does not panic, whatever
+// its input.
+func Unpack(b []byte) (Tuple, error) {
+	t, _, e := decodeTuple(b, 0, false, 0)
+	if e != nil {
+		return nil, e
+	}
 	return t, nil
 }
 
 // Range returns the KeyRange that describes the keys encoding tuples that
 // strictly begin with t (that is, all tuples of greater length than t of which
 // t is a prefix). Range will panic if the tuple contains an element of any type
-// other than []byte, string, int64, int, or nil.
+// other than []byte, string, int64, int, uint64, uint, *big.Int, float32,
+// float64, bool, UUID, Versionstamp, Desc, Tuple or nil, or if it contains an
+// incomplete Versionstamp.
 func (t Tuple) Range() fdb.KeyRange {
 	p := t.Pack()
 
@@ -0,0 +1,460 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime"
+	"testing"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// Encodings produced by the Python and Java bindings for the same tuples.
+var packTests = []struct {
+	t tuple.Tuple
+	enc string
+}{
+	{tuple.Tuple{nil}, "00"},
+	{tuple.Tuple{int64(0)}, "14"},
+	{tuple.Tuple{int64(-1)}, "13fe"},
+	{tuple.Tuple{int64(math.MaxInt64)}, "1c7fffffffffffffff"},
+	{tuple.Tuple{int64(math.MinInt64)}, "0c7fffffffffffffff"},
+	{tuple.Tuple{uint64(1 << 63)}, "1c8000000000000000"},
+	{tuple.Tuple{uint64(math.MaxUint64)}, "1cffffffffffffffff"},
+	{tuple.Tuple{[]byte("foo\x00bar")}, "01666f6f00ff62617200"},
+	{tuple.Tuple{"hello"}, "0268656c6c6f00"},
+	{tuple.Tuple{float32(1.0)}, "20bf800000"},
+	{tuple.Tuple{float32(-1.0)}, "20407fffff"},
+	{tuple.Tuple{float32(0.0)}, "2080000000"},
+	{tuple.Tuple{float32(math.Inf(1))}, "20ff800000"},
+	{tuple.Tuple{float32(math.Inf(-1))}, "20007fffff"},
+	{tuple.Tuple{float64(1.0)}, "21bff0000000000000"},
+	{tuple.Tuple{float64(-1.0)}, "21400fffffffffffff"},
+	{tuple.Tuple{float64(0.0)}, "218000000000000000"},
+	{tuple.Tuple{math.Copysign(0, -1)}, "217fffffffffffffff"},
+	{tuple.Tuple{float64(3.14)}, "21c0091eb851eb851f"},
+	{tuple.Tuple{math.Inf(1)}, "21fff0000000000000"},
+	{tuple.Tuple{math.Inf(-1)}, "21000fffffffffffff"},
+	{tuple.Tuple{false, true}, "2627"},
+	{tuple.Tuple{tuple.UUID{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}}, "30123456789abcdef00123456789abcdef"},
+	{tuple.Tuple{tuple.Versionstamp{[10]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 2}, 3}}, "33000000000000000100020003"},
+	{tuple.Tuple{tuple.Tuple{}}, "0500"},
+	{tuple.Tuple{tuple.Tuple{nil}}, "0500ff00"},
+	{tuple.Tuple{tuple.Tuple{nil, []byte("foo")}, nil}, "0500ff01666f6f000000"},
+	{tuple.Tuple{"a", tuple.Tuple{int64(1), tuple.Tuple{[]byte("\x00")}}}, "026100051501050100ff000000"},
+}
+
+func TestPack(t *testing.T) {
+	for _, tt := range packTests {
+		enc, _ := hex.DecodeString(tt.enc)
+
+		if p := tt.t.Pack(); !bytes.Equal(p, enc) {
+			t.Errorf("%v.Pack() = %x, want %s", tt.t, p, tt.enc)
+		}
+
+		u, e := tuple.Unpack(enc)
+		if e != nil {
+			t.Errorf("Unpack(%s) returned error: %v", tt.enc, e)
+			continue
+		}
+		if !reflect.DeepEqual(u, tt.t) {
+			t.Errorf("Unpack(%s) = %#v, want %#v", tt.enc, u, tt.t)
+		}
+	}
+}
+
+func TestFloatNaN(t *testing.T) {
+	// The canonical quiet NaN, as produced by the other bindings.
+	nan := math.Float64frombits(0x7ff8000000000000)
+
+	p := tuple.Tuple{nan, float32(nan)}.Pack()
+	if want := "21fff8000000000000" + "20ffc00000"; hex.EncodeToString(p) != want {
+		t.Fatalf("Pack(NaN) = %x, want %s", p, want)
+	}
+
+	u, e := tuple.Unpack(p)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if d, ok := u[0].(float64); !ok || math.Float64bits(d) != math.Float64bits(nan) {
+		t.Errorf("Unpack(NaN)[0] = %#v, want float64 NaN", u[0])
+	}
+	if f, ok := u[1].(float32); !ok || !math.IsNaN(float64(f)) {
+		t.Errorf("Unpack(NaN)[1] = %#v, want float32 NaN", u[1])
+	}
+}
+
+func TestFloatOrdering(t *testing.T) {
+	doubles := []float64{math.Inf(-1), -1e300, -2.5, -1, -1e-300, math.Copysign(0, -1), 0, 1e-300, 1, 2.5, 1e300, math.Inf(1), math.NaN()}
+	for i := 1; i < len(doubles); i++ {
+		a := tuple.Tuple{doubles[i-1]}.Pack()
+		b := tuple.Tuple{doubles[i]}.Pack()
+		if bytes.Compare(a, b) >= 0 {
+			t.Errorf("encoding of %v (%x) does not sort before %v (%x)", doubles[i-1], a, doubles[i], b)
+		}
+	}
+
+	floats := []float32{float32(math.Inf(-1)), -2.5, -1, 0, 1, 2.5, float32(math.Inf(1))}
+	for i := 1; i < len(floats); i++ {
+		a := tuple.Tuple{floats[i-1]}.Pack()
+		b := tuple.Tuple{floats[i]}.Pack()
+		if bytes.Compare(a, b) >= 0 {
+			t.Errorf("encoding of %v (%x) does not sort before %v (%x)", floats[i-1], a, floats[i], b)
+		}
+	}
+}
+
+func TestNestedOrdering(t *testing.T) {
+	tuples := []tuple.Tuple{
+		{tuple.Tuple{"a"}},
+		{tuple.Tuple{"a", nil}},
+		{tuple.Tuple{"a", nil}, nil},
+		{tuple.Tuple{"a", tuple.Tuple{}}, int64(1)},
+		{tuple.Tuple{"a", int64(1)}},
+		{tuple.Tuple{"b"}},
+	}
+	for i := 1; i < len(tuples); i++ {
+		a := tuples[i-1].Pack()
+		b := tuples[i].Pack()
+		if bytes.Compare(a, b) >= 0 {
+			t.Errorf("encoding of %v (%x) does not sort before %v (%x)", tuples[i-1], a, tuples[i], b)
+		}
+	}
+
+	kr := tuple.Tuple{tuple.Tuple{"a", nil}}.Range()
+	k := tuple.Tuple{tuple.Tuple{"a", nil}, int64(7)}.Pack()
+	if bytes.Compare(k, kr.BeginKey()) < 0 || bytes.Compare(k, kr.EndKey()) >= 0 {
+		t.Errorf("%x is not within %v", k, kr)
+	}
+}
+
+func TestDesc(t *testing.T) {
+	tuples := []tuple.Tuple{
+		{"scores", tuple.Desc{int64(100)}, "b"},
+		{"scores", tuple.Desc{int64(100)}, "c"},
+		{"scores", tuple.Desc{int64(7)}, "a"},
+		{"scores", tuple.Desc{int64(-3)}, "a"},
+		{"scores", tuple.Desc{nil}, "a"},
+		{"times", tuple.Desc{tuple.Desc{"a"}}},
+		{"times", tuple.Desc{tuple.Desc{"b"}}},
+		{"times", tuple.Desc{"b"}},
+		{"times", tuple.Desc{"a\x00"}},
+		{"times", tuple.Desc{"a"}, int64(1)},
+		{"times", tuple.Desc{"a"}, int64(2)},
+		{tuple.Tuple{tuple.Desc{nil}, tuple.Desc{int64(1)}}, nil},
+		{tuple.Tuple{tuple.Desc{nil}, tuple.Desc{int64(0)}}},
+	}
+	for i, tup := range tuples {
+		p := tup.Pack()
+		if u, e := tuple.Unpack(p); e != nil || !reflect.DeepEqual(u, tup) {
+			t.Errorf("Unpack(%x) = %#v, %v, want %#v", p, u, e, tup)
+		}
+		if i > 0 && bytes.Compare(tuples[i-1].Pack(), p) >= 0 {
+			t.Errorf("encoding of %v (%x) does not sort before %v (%x)", tuples[i-1], tuples[i-1].Pack(), tup, p)
+		}
+	}
+
+	if p := (tuple.Tuple{tuple.Desc{int64(1)}}).Pack(); !bytes.Equal(p, []byte{0x40, 0xea, 0xfe, 0xff}) {
+		t.Errorf("Pack(Desc{1}) = %x, want 40eafeff", p)
+	}
+
+	defer func() {
+		if _, ok := recover().(*tuple.ElementError); !ok {
+			t.Error("packing an incomplete Versionstamp in a Desc did not panic with an *ElementError")
+		}
+	}()
+	tuple.Tuple{tuple.Desc{tuple.IncompleteVersionstamp(0)}}.Pack()
+}
+
+var malformedTests = []struct {
+	enc string
+	offset int
+	code byte
+}{
+	{"01666f6f", 0, 0x01},
+	{"14026162", 1, 0x02},
+	{"1601", 0, 0x16},
+	{"1d0901", 0, 0x1d},
+	{"0b", 0, 0x0b},
+	{"0c00", 0, 0x0c},
+	{"20bf80", 0, 0x20},
+	{"1421bff0", 1, 0x21},
+	{"301234", 0, 0x30},
+	{"33ffff", 0, 0x33},
+	{"0500ff", 0, 0x05},
+	{"050201", 1, 0x02},
+	{"05051c01", 2, 0x1c},
+	{"14ff", 1, 0xff},
+	{"40", 0, 0x40},
+	{"40fe9e", 1, 0x01},
+	{"40eb", 0, 0x40},
+	{"40ebfe", 0, 0x40},
+	{"1440ebff40", 4, 0x40},
+}
+
+func TestUnpackMalformed(t *testing.T) {
+	for _, tt := range malformedTests {
+		enc, _ := hex.DecodeString(tt.enc)
+
+		_, e := tuple.Unpack(enc)
+		de, ok := e.(*tuple.DecodeError)
+		if !ok {
+			t.Errorf("Unpack(%s) returned %v, want *DecodeError", tt.enc, e)
+			continue
+		}
+		if de.Offset != tt.offset || de.Code != tt.code {
+			t.Errorf("Unpack(%s) failed at offset %d (code %02x), want offset %d (code %02x)", tt.enc, de.Offset, de.Code, tt.offset, tt.code)
+		}
+	}
+}
+
+func TestUnpackNestedDesc(t *testing.T) {
+	var el interface{} = "x"
+	for range(2000) {
+		el = tuple.Desc{el}
+	}
+	p := tuple.Tuple{el}.Pack()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	u, e := tuple.Unpack(p)
+	runtime.ReadMemStats(&after)
+
+	if e != nil {
+		t.Fatalf("Unpack returned %v", e)
+	}
+	if !reflect.DeepEqual(u, tuple.Tuple{el}) {
+		t.Errorf("Unpack did not return the packed tuple")
+	}
+	// Decoding should take time and space linear in the size of the key.
+	if n := after.TotalAlloc - before.TotalAlloc; n > uint64(64 * len(p)) {
+		t.Errorf("Unpack of a %d byte key allocated %d bytes", len(p), n)
+	}
+}
+
+func FuzzUnpack(f *testing.F) {
+	for _, tt := range packTests {
+		enc, _ := hex.DecodeString(tt.enc)
+		f.Add(enc)
+	}
+	for _, tt := range malformedTests {
+		enc, _ := hex.DecodeString(tt.enc)
+		f.Add(enc)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		_, e := tuple.Unpack(b)
+		if e != nil {
+			if _, ok := e.(*tuple.DecodeError); !ok {
+				t.Errorf("Unpack(%x) returned %T, want *DecodeError", b, e)
+			}
+		}
+	})
+}
+
+func TestUUIDString(t *testing.T) {
+	u := tuple.UUID{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	if s := u.String(); s != "12345678-9abc-def0-0123-456789abcdef" {
+		t.Errorf("UUID.String() = %s", s)
+	}
+
+	// A UUID must not unpack as (or compare equal to) a byte string.
+	p := tuple.Tuple{u}.Pack()
+	if bytes.Equal(p, tuple.Tuple{u[:]}.Pack()) {
+		t.Error("UUID and []byte encodings are identical")
+	}
+}
+
+func bigInt(s string) *big.Int {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(s)
+	}
+	return i
+}
+
+func TestBigInt(t *testing.T) {
+	tests := []struct {
+		i *big.Int
+		enc string
+	}{
+		{bigInt("-9223372036854775809"), "0c7ffffffffffffffe"},
+		{bigInt("-18446744073709551615"), "0c0000000000000000"},
+		{bigInt("18446744073709551616"), "1d09010000000000000000"},
+		{bigInt("-18446744073709551616"), "0bf6feffffffffffffffff"},
+		{bigInt("-18446744073709551617"), "0bf6fefffffffffffffffe"},
+	}
+
+	for _, tt := range tests {
+		p := tuple.Tuple{tt.i}.Pack()
+		if hex.EncodeToString(p) != tt.enc {
+			t.Errorf("Pack(%v) = %x, want %s", tt.i, p, tt.enc)
+		}
+
+		u, e := tuple.Unpack(p)
+		if e != nil {
+			t.Errorf("Unpack(%x) returned error: %v", p, e)
+			continue
+		}
+		if i, ok := u[0].(*big.Int); !ok || i.Cmp(tt.i) != 0 {
+			t.Errorf("Unpack(%x) = %#v, want %v", p, u[0], tt.i)
+		}
+	}
+
+	// Values that fit in a smaller type must share its encoding and unpack
+	// as that type.
+	small := []struct {
+		i *big.Int
+		want interface{}
+	}{
+		{big.NewInt(0), int64(0)},
+		{big.NewInt(-300), int64(-300)},
+		{big.NewInt(math.MinInt64), int64(math.MinInt64)},
+		{new(big.Int).SetUint64(math.MaxUint64), uint64(math.MaxUint64)},
+	}
+
+	for _, tt := range small {
+		p := tuple.Tuple{tt.i}.Pack()
+		if want := (tuple.Tuple{tt.want}).Pack(); !bytes.Equal(p, want) {
+			t.Errorf("Pack(%v) = %x, want %x", tt.i, p, want)
+		}
+		if u, _ := tuple.Unpack(p); !reflect.DeepEqual(u[0], tt.want) {
+			t.Errorf("Unpack(%x) = %#v, want %#v", p, u[0], tt.want)
+		}
+	}
+
+	// A nil *big.Int, or one too large for its length to fit in a byte, is
+	// not a valid element.
+	for _, i := range([]*big.Int{nil, new(big.Int).Lsh(big.NewInt(1), 0xff * 8)}) {
+		func() {
+			defer func() {
+				if _, ok := recover().(*tuple.ElementError); !ok {
+					t.Errorf("Pack(%v) did not panic with an *ElementError", i)
+				}
+			}()
+			tuple.Tuple{i}.Pack()
+		}()
+	}
+}
+
+func TestIntOrdering(t *testing.T) {
+	ints := []interface{}{
+		bigInt("-100000000000000000000000"),
+		bigInt("-18446744073709551616"),
+		bigInt("-18446744073709551615"),
+		int64(math.MinInt64),
+		int64(-1),
+		int64(0),
+		uint64(math.MaxInt64),
+		uint64(math.MaxUint64),
+		bigInt("18446744073709551616"),
+		bigInt("100000000000000000000000"),
+	}
+	for i := 1; i < len(ints); i++ {
+		a := tuple.Tuple{ints[i-1]}.Pack()
+		b := tuple.Tuple{ints[i]}.Pack()
+		if bytes.Compare(a, b) >= 0 {
+			t.Errorf("encoding of %v (%x) does not sort before %v (%x)", ints[i-1], a, ints[i], b)
+		}
+	}
+}
+
+func TestPackWithVersionstamp(t *testing.T) {
+	tup := tuple.Tuple{"prefix", tuple.Tuple{int64(1), tuple.IncompleteVersionstamp(7)}}
+
+	p, off, e := tup.PackWithVersionstamp()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if want := "0270726566697800051501" + "33ffffffffffffffffffff0007" + "00"; hex.EncodeToString(p) != want {
+		t.Errorf("PackWithVersionstamp() = %x, want %s", p, want)
+	}
+	if off != 12 {
+		t.Errorf("PackWithVersionstamp() offset = %d, want 12", off)
+	}
+
+	u, e := tuple.Unpack(p)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(u, tup) {
+		t.Errorf("Unpack(%x) = %#v, want %#v", p, u, tup)
+	}
+
+	if _, _, e := (tuple.Tuple{"no versionstamp"}).PackWithVersionstamp(); e == nil {
+		t.Error("PackWithVersionstamp() with no incomplete versionstamp returned no error")
+	}
+	if _, _, e := (tuple.Tuple{tuple.IncompleteVersionstamp(0), tuple.IncompleteVersionstamp(1)}).PackWithVersionstamp(); e == nil {
+		t.Error("PackWithVersionstamp() with two incomplete versionstamps returned no error")
+	}
+}
+
+func TestPackTo(t *testing.T) {
+	prefix := []byte("prefix")
+	tup := tuple.Tuple{"abc", int64(-42), uint64(1 << 63), 2.5, tuple.Tuple{nil, []byte("\x00")}}
+
+	p := tup.PackTo(append([]byte(nil), prefix...))
+	if want := append(append([]byte(nil), prefix...), tup.Pack()...); !bytes.Equal(p, want) {
+		t.Errorf("PackTo() = %x, want %x", p, want)
+	}
+
+	buf := make([]byte, 0, 128)
+	if n := testing.AllocsPerRun(100, func() { tup.PackTo(buf[:0]) }); n != 0 {
+		t.Errorf("PackTo() into a large enough buffer made %v allocations", n)
+	}
+
+	var enc tuple.Encoder
+	enc.Pack(tup)
+	if n := testing.AllocsPerRun(100, func() { enc.Pack(tup) }); n != 0 {
+		t.Errorf("(*Encoder).Pack() made %v allocations", n)
+	}
+	if p := enc.Pack(tup); !bytes.Equal(p, tup.Pack()) {
+		t.Errorf("(*Encoder).Pack() = %x, want %x", p, tup.Pack())
+	}
+}
+
+var benchTuple = tuple.Tuple{"users", int64(1234567), "email", []byte("someone@example.com"), 3.14159}
+
+func BenchmarkPack(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchTuple.Pack()
+	}
+}
+
+func BenchmarkEncoderPack(b *testing.B) {
+	var enc tuple.Encoder
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enc.Pack(benchTuple)
+	}
+}
+
+func BenchmarkUnpack(b *testing.B) {
+	p := benchTuple.Pack()
+	for i := 0; i < b.N; i++ {
+		tuple.Unpack(p)
+	}
+}
@@ -0,0 +1,293 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"errors"
+	"sync"
+)
+
+// readySource is what a Future waits on: either the future of a single C
+// library operation, or a promise completed by Go code.
+type readySource interface {
+	Ready() <-chan struct{}
+	onReady(cb func())
+	Cancel()
+}
+
+// Future represents the asynchronous result, of type T, of one or more
+// database operations. A Future is obtained from one of the concrete future
+// types of this package with its AsFuture method, and may be composed with Map
+// and Then. Future is a lightweight object that may be efficiently copied, and
+// is safe for concurrent use by multiple goroutines.
+type Future[T any] struct {
+	src readySource
+	get func() (T, error)
+}
+
+func newFuture[T any](f *future, get func() (T, error)) Future[T] {
+	return Future[T]{src: f, get: get}
+}
+
+// AsFuture returns f as a Future.
+func (f FutureValue) AsFuture() Future[[]byte] {
+	return newFuture(f.future, f.GetWithError)
+}
+
+// AsFuture returns f as a Future.
+func (f FutureKey) AsFuture() Future[Key] {
+	return newFuture(f.future, f.GetWithError)
+}
+
+// AsFuture returns f as a Future, whose value is always struct{}{}.
+func (f FutureNil) AsFuture() Future[struct{}] {
+	return newFuture(f.future, func() (struct{}, error) {
+		return struct{}{}, f.GetWithError()
+	})
+}
+
+// AsFuture returns f as a Future.
+func (f FutureVersion) AsFuture() Future[int64] {
+	return newFuture(f.future, f.GetWithError)
+}
+
+// AsFuture returns f as a Future.
+func (f FutureStringArray) AsFuture() Future[[]string] {
+	return newFuture(f.future, f.GetWithError)
+}
+
+// Get returns the value of the future, or an error if any of the operations
+// it depends on did not successfully complete. The current goroutine will be
+// blocked until the future is ready.
+func (f Future[T]) Get() (T, error) {
+	<-f.src.Ready()
+	return f.get()
+}
+
+// MustGet returns the value of the future, or panics with the error that Get
+// would return. The current goroutine will be blocked until the future is
+// ready.
+func (f Future[T]) MustGet() T {
+	v, e := f.Get()
+	if e != nil {
+		panic(e)
+	}
+	return v
+}
+
+// OnReady arranges for fn to be called with the value of the future and any
+// error once the future is ready, without blocking the calling goroutine. fn is
+// called in a goroutine of its own, and so may block.
+func (f Future[T]) OnReady(fn func(T, error)) {
+	f.src.onReady(func() {
+		fn(f.get())
+	})
+}
+
+// Ready returns a channel that is closed when the future becomes ready, for
+// use in a select statement.
+func (f Future[T]) Ready() <-chan struct{} {
+	return f.src.Ready()
+}
+
+// BlockUntilReady blocks the calling goroutine until the future is ready.
+func (f Future[T]) BlockUntilReady() {
+	<-f.src.Ready()
+}
+
+// IsReady returns true if the future is ready, and false otherwise, without
+// blocking.
+func (f Future[T]) IsReady() bool {
+	select {
+	case <-f.src.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+// Cancel cancels the operations the future depends on that have not yet
+// completed. Getting the value of a cancelled future returns an error.
+func (f Future[T]) Cancel() {
+	f.src.Cancel()
+}
+
+// recoverError recovers a panicked FDB Error (or error wrapping one), as
+// GetOrPanic and MustGet produce, into *e. Other panics are not recovered.
+func recoverError(e *error) {
+	if r := recover(); r != nil {
+		if re, ok := r.(error); ok && errors.As(re, new(Error)) {
+			*e = re
+		} else {
+			panic(r)
+		}
+	}
+}
+
+// Map returns a Future whose value is that of f transformed by fn. fn is called
+// at most once, when the value of the returned future is first needed, and
+// not at all if f fails. A panicked FDB Error from fn (as from GetOrPanic) is
+// returned as the error of the future.
+func Map[T, U any](f Future[T], fn func(T) (U, error)) Future[U] {
+	var once sync.Once
+	var u U
+	var e error
+
+	return Future[U]{src: f.src, get: func() (U, error) {
+		once.Do(func() {
+			defer recoverError(&e)
+
+			var t T
+			if t, e = f.get(); e == nil {
+				u, e = fn(t)
+			}
+		})
+		return u, e
+	}}
+}
+
+// Then returns a Future for the result of a dependent operation. Once f is
+// ready, fn is called with its value to start the operation, and the returned
+// Future becomes ready when the Future returned by fn does. If f fails, fn is
+// not called. No goroutine is blocked while either future is pending.
+//
+// Cancelling the returned Future cancels f and, if it has been started, the
+// future returned by fn.
+func Then[T, U any](f Future[T], fn func(T) Future[U]) Future[U] {
+	p := newPromise(f.Cancel)
+	var u U
+	var e error
+
+	f.OnReady(func(t T, et error) {
+		if et != nil {
+			e = et
+			p.fire()
+			return
+		}
+
+		var g Future[U]
+		func() {
+			defer recoverError(&e)
+			g = fn(t)
+		}()
+		if e != nil {
+			p.fire()
+			return
+		}
+
+		p.addCancel(g.Cancel)
+		g.OnReady(func(v U, eg error) {
+			u, e = v, eg
+			p.fire()
+		})
+	})
+
+	return Future[U]{src: p, get: func() (U, error) {
+		return u, e
+	}}
+}
+
+// callbackList holds the callbacks to be started when something becomes ready.
+type callbackList struct {
+	mutex sync.Mutex
+	fired bool
+	cbs []func()
+}
+
+// add arranges for cb to be called in a goroutine of its own once fire is
+// called, or at once if it already has been.
+func (l *callbackList) add(cb func()) {
+	l.mutex.Lock()
+	if !l.fired {
+		l.cbs = append(l.cbs, cb)
+		l.mutex.Unlock()
+		return
+	}
+	l.mutex.Unlock()
+
+	go cb()
+}
+
+func (l *callbackList) fire() {
+	l.mutex.Lock()
+	l.fired = true
+	cbs := l.cbs
+	l.cbs = nil
+	l.mutex.Unlock()
+
+	for _, cb := range(cbs) {
+		go cb()
+	}
+}
+
+// promise is a readySource made ready by Go code, for futures that depend on
+// more than one operation.
+type promise struct {
+	ready chan struct{}
+	callbacks callbackList
+	mutex sync.Mutex
+	cancelled bool
+	cancels []func()
+}
+
+func newPromise(cancel func()) *promise {
+	return &promise{ready: make(chan struct{}), cancels: []func(){cancel}}
+}
+
+func (p *promise) Ready() <-chan struct{} {
+	return p.ready
+}
+
+func (p *promise) onReady(cb func()) {
+	p.callbacks.add(cb)
+}
+
+func (p *promise) fire() {
+	close(p.ready)
+	p.callbacks.fire()
+}
+
+// addCancel adds a function to be called when p is cancelled, or calls it at
+// once if p already has been.
+func (p *promise) addCancel(cancel func()) {
+	p.mutex.Lock()
+	if !p.cancelled {
+		p.cancels = append(p.cancels, cancel)
+		p.mutex.Unlock()
+		return
+	}
+	p.mutex.Unlock()
+
+	cancel()
+}
+
+func (p *promise) Cancel() {
+	p.mutex.Lock()
+	p.cancelled = true
+	cancels := p.cancels
+	p.cancels = nil
+	p.mutex.Unlock()
+
+	for _, cancel := range(cancels) {
+		cancel()
+	}
+}
@@ -0,0 +1,166 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"errors"
+	"testing"
+)
+
+// settable returns a Future backed by a promise, and a function that makes it
+// ready with the given value and error.
+func settable[T any]() (Future[T], func(T, error)) {
+	cancelled := errors.New("cancelled")
+	var v T
+	var e error
+
+	var p *promise
+	p = newPromise(func() {
+		select {
+		case <-p.ready:
+		default:
+			e = cancelled
+			p.fire()
+		}
+	})
+
+	f := Future[T]{src: p, get: func() (T, error) {
+		return v, e
+	}}
+	return f, func(nv T, ne error) {
+		v, e = nv, ne
+		p.fire()
+	}
+}
+
+func TestFutureMap(t *testing.T) {
+	f, set := settable[int]()
+
+	calls := 0
+	g := Map(f, func(i int) (string, error) {
+		calls += 1
+		return string(rune('a' + i)), nil
+	})
+
+	if g.IsReady() {
+		t.Fatalf("Mapped future is ready before its source")
+	}
+
+	set(2, nil)
+	for i := 0; i < 3; i += 1 {
+		if v := g.MustGet(); v != "c" {
+			t.Errorf("Mapped future has value %q, want %q", v, "c")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Map function called %d times, want 1", calls)
+	}
+}
+
+func TestFutureMapErrors(t *testing.T) {
+	f, set := settable[int]()
+	called := false
+	g := Map(f, func(i int) (int, error) {
+		called = true
+		return i, nil
+	})
+	set(0, ErrorNotCommitted)
+	if _, e := g.Get(); e != ErrorNotCommitted {
+		t.Errorf("Mapped future of failed future returned %v", e)
+	}
+	if called {
+		t.Errorf("Map function called for failed future")
+	}
+
+	f, set = settable[int]()
+	g = Map(f, func(i int) (int, error) {
+		panic(&OpError{Op: "Get", Err: ErrorTransactionTooOld})
+	})
+	set(0, nil)
+	if _, e := g.Get(); !errors.Is(e, ErrorTransactionTooOld) {
+		t.Errorf("Mapped future did not recover panicked Error, returned %v", e)
+	}
+}
+
+func TestFutureThen(t *testing.T) {
+	f, setF := settable[int]()
+	g, setG := settable[string]()
+
+	started := make(chan int, 1)
+	h := Then(f, func(i int) Future[string] {
+		started <- i
+		return g
+	})
+
+	done := make(chan struct{})
+	var got string
+	h.OnReady(func(v string, e error) {
+		got = v
+		close(done)
+	})
+
+	setF(7, nil)
+	if i := <-started; i != 7 {
+		t.Fatalf("Then function called with %d, want 7", i)
+	}
+	if h.IsReady() {
+		t.Fatalf("Then future is ready before the dependent future")
+	}
+
+	setG("seven", nil)
+	<-done
+	if got != "seven" {
+		t.Errorf("OnReady called with %q, want %q", got, "seven")
+	}
+	if v, e := h.Get(); v != "seven" || e != nil {
+		t.Errorf("Then future returned %q, %v", v, e)
+	}
+}
+
+func TestFutureThenError(t *testing.T) {
+	f, set := settable[int]()
+	h := Then(f, func(i int) Future[int] {
+		t.Errorf("Then function called for failed future")
+		return f
+	})
+
+	set(0, ErrorFutureVersion)
+	if _, e := h.Get(); e != ErrorFutureVersion {
+		t.Errorf("Then future of failed future returned %v", e)
+	}
+}
+
+func TestFutureThenCancel(t *testing.T) {
+	f, _ := settable[int]()
+	h := Then(f, func(i int) Future[int] {
+		t.Errorf("Then function called for cancelled future")
+		return f
+	})
+
+	h.Cancel()
+	if !f.IsReady() {
+		t.Errorf("Cancelling Then future did not cancel its source")
+	}
+	if _, e := h.Get(); e == nil {
+		t.Errorf("Cancelled Then future returned no error")
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"github.com/FoundationDB/fdb-go/fdb"
 	"github.com/FoundationDB/fdb-go/fdb/tuple"
 	"log"
+	"math/big"
 	"fmt"
 	"os"
 	"strings"
@@ -42,13 +44,22 @@ func newStackMachine(prefix []byte, verbose bool) *StackMachine {
 	return &sm
 }
 
+// fdbError returns the fdb.Error of a recovered panic value, which may be an
+// fdb.Error or an error wrapping one.
+func fdbError(r interface{}) (fdb.Error, bool) {
+	var ep fdb.Error
+	if e, ok := r.(error); ok && errors.As(e, &ep) {
+		return ep, true
+	}
+	return 0, false
+}
+
 func (sm *StackMachine) waitAndPop() (ret stackEntry) {
 	defer func() {
 		if r := recover(); r != nil {
-			switch r := r.(type) {
-			case fdb.Error:
-				ret.item = tuple.Tuple{[]byte("ERROR"), []byte(fmt.Sprintf("%d", int(r)))}.Pack()
-			default:
+			if ep, ok := fdbError(r); ok {
+				ret.item = tuple.Tuple{[]byte("ERROR"), []byte(fmt.Sprintf("%d", int(ep)))}.Pack()
+			} else {
 				panic(r)
 			}
 		}
@@ -56,7 +67,7 @@ func (sm *StackMachine) waitAndPop() (ret stackEntry) {
 
 	ret, sm.stack = sm.stack[len(sm.stack) - 1], sm.stack[:len(sm.stack) - 1]
 	switch el := ret.item.(type) {
-	case int64, []byte, string:
+	case int64, uint64, *big.Int, []byte, string, float32, float64, bool, tuple.UUID, tuple.Versionstamp, tuple.Tuple:
 	case fdb.Key:
 		ret.item = []byte(el)
 	case fdb.FutureNil:
@@ -120,10 +131,9 @@ func (sm *StackMachine) dumpStack() {
 func (sm *StackMachine) processInst(idx int, inst tuple.Tuple) {
 	defer func() {
 		if r := recover(); r != nil {
-			switch r := r.(type) {
-			case fdb.Error:
-				sm.store(idx, tuple.Tuple{[]byte("ERROR"), []byte(fmt.Sprintf("%d", int(r)))}.Pack())
-			default:
+			if ep, ok := fdbError(r); ok {
+				sm.store(idx, tuple.Tuple{[]byte("ERROR"), []byte(fmt.Sprintf("%d", int(ep)))}.Pack())
+			} else {
 				panic(r)
 			}
 		}
@@ -397,7 +407,7 @@ func (sm *StackMachine) processInst(idx int, inst tuple.Tuple) {
 		db.Transact(func (tr fdb.Transaction) (interface{}, error) {
 			v := tr.GetRange(er, fdb.RangeOptions{}).GetSliceOrPanic()
 			if len(v) != 0 {
-				panic(fdb.Error(1020))
+				panic(fdb.ErrorNotCommitted)
 			}
 			return nil, nil
 		})